@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	_ "time/tzdata"
 
 	"github.com/tekig/mog-go/internal/app"
 )
@@ -20,6 +21,15 @@ func main() {
 }
 
 func run(ctx context.Context) error {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "sounds":
+			return app.Sounds(ctx, os.Args[2:])
+		default:
+			return fmt.Errorf("%s: %w", os.Args[1], app.ErrUnknownCommand)
+		}
+	}
+
 	app, err := app.New()
 	if err != nil {
 		return fmt.Errorf("app: %w", err)
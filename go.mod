@@ -4,12 +4,14 @@ go 1.20
 
 require (
 	github.com/bwmarrin/discordgo v0.27.1
+	github.com/pion/rtp v1.8.3
 	github.com/pion/webrtc/v4 v4.0.0-beta.7
+	golang.org/x/exp v0.0.0-20240823005443-9b4947da3948
 )
 
 require (
 	github.com/gorilla/websocket v1.4.2 // indirect
+	github.com/pion/randutil v0.1.0 // indirect
 	golang.org/x/crypto v0.14.0 // indirect
-	golang.org/x/exp v0.0.0-20240823005443-9b4947da3948 // indirect
 	golang.org/x/sys v0.13.0 // indirect
 )
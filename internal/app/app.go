@@ -44,7 +44,9 @@ func New() (*App, error) {
 	})
 
 	client.Identify.Intents = discordgo.MakeIntent(
-		discordgo.IntentGuildMessages |
+		discordgo.IntentGuilds |
+			discordgo.IntentGuildMessages |
+			discordgo.IntentMessageContent |
 			discordgo.IntentGuildVoiceStates |
 			discordgo.IntentGuildMessageReactions,
 	)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
 
 	boommessage "github.com/tekig/mog-go/internal/boom-message"
 	welcomevoice "github.com/tekig/mog-go/internal/welcome-voice"
@@ -38,6 +39,71 @@ func NewConfig() (*Config, error) {
 		config.WelcomeVoice.VoiceDir = path.Join(config.Store, "welcome-voice")
 	}
 
+	if config.WelcomeVoice.DownloadTimeout.Duration == 0 {
+		config.WelcomeVoice.DownloadTimeout.Duration = 30 * time.Second
+	}
+
+	if len(config.WelcomeVoice.Random) == 0 {
+		config.WelcomeVoice.Random = []welcomevoice.RandomProviderConfig{{
+			Type:    welcomevoice.RandomProviderHTTP,
+			URL:     "http://api.cleanvoice.ru/myinstants/?type=file",
+			Retries: 1,
+		}}
+	}
+
+	if config.WelcomeVoice.FallbackPolicy == "" {
+		config.WelcomeVoice.FallbackPolicy = welcomevoice.FallbackSticky
+	}
+
+	if config.WelcomeVoice.CommandPrefix == "" {
+		config.WelcomeVoice.CommandPrefix = "!mog"
+	}
+
+	mix := &config.WelcomeVoice.Mix
+	if mix.Window.Duration == 0 {
+		mix.Window.Duration = 2 * time.Second
+	}
+	if mix.ClipLength.Duration == 0 {
+		mix.ClipLength.Duration = 3 * time.Second
+	}
+
+	if config.WelcomeVoice.HistorySize == nil {
+		historySize := 5
+		config.WelcomeVoice.HistorySize = &historySize
+	}
+
+	if config.WelcomeVoice.Soundboard.Cooldown.Duration == 0 {
+		config.WelcomeVoice.Soundboard.Cooldown.Duration = 30 * time.Second
+	}
+
+	moderation := &config.WelcomeVoice.Moderation
+	if moderation.ApproveEmoji == "" {
+		moderation.ApproveEmoji = "✅"
+	}
+	if moderation.RejectEmoji == "" {
+		moderation.RejectEmoji = "❌"
+	}
+
+	convert := &config.WelcomeVoice.Convert
+	if convert.Loudness == 0 {
+		convert.Loudness = -16
+	}
+	if convert.TruePeak == 0 {
+		convert.TruePeak = -1.5
+	}
+	if convert.LoudnessRange == 0 {
+		convert.LoudnessRange = 11
+	}
+	if convert.SilenceThreshold == 0 {
+		convert.SilenceThreshold = -50
+	}
+	if convert.QueueSize == 0 {
+		convert.QueueSize = 20
+	}
+	if convert.Timeout.Duration == 0 {
+		convert.Timeout.Duration = 2 * time.Minute
+	}
+
 	if config.BoomMessage.MessageDir == "" {
 		config.BoomMessage.MessageDir = path.Join(config.Store, "boom-message")
 	}
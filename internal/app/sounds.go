@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	welcomevoice "github.com/tekig/mog-go/internal/welcome-voice"
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrMissingArchive = errors.New("missing archive path")
+	ErrInvalidMapping = errors.New("invalid user mapping")
+)
+
+// Sounds runs maintenance commands on the welcome voice store.
+func Sounds(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("sounds: %w, expected reencode, export or import", ErrUnknownCommand)
+	}
+
+	config, err := NewConfig()
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+
+	switch args[0] {
+	case "reencode":
+		if err := welcomevoice.Reencode(ctx, config.WelcomeVoice, logger); err != nil {
+			return fmt.Errorf("reencode: %w", err)
+		}
+	case "export":
+		if len(args) < 2 {
+			return fmt.Errorf("export: %w", ErrMissingArchive)
+		}
+
+		if err := welcomevoice.Export(ctx, config.WelcomeVoice, logger, args[1]); err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+	case "import":
+		if len(args) < 2 {
+			return fmt.Errorf("import: %w", ErrMissingArchive)
+		}
+
+		userIDs, err := parseUserMap(args[2:])
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+
+		if err := welcomevoice.Import(ctx, config.WelcomeVoice, logger, args[1], userIDs); err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+	default:
+		return fmt.Errorf("sounds %s: %w", args[0], ErrUnknownCommand)
+	}
+
+	return nil
+}
+
+// parseUserMap reads "old=new" pairs mapping user IDs of an archive to the
+// ones to import as.
+func parseUserMap(args []string) (map[string]string, error) {
+	userIDs := make(map[string]string, len(args))
+	for _, arg := range args {
+		from, to, ok := strings.Cut(arg, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("%q: %w, expected old=new", arg, ErrInvalidMapping)
+		}
+		if strings.Trim(from, "0123456789") != "" || strings.Trim(to, "0123456789") != "" {
+			return nil, fmt.Errorf("%q: %w, user IDs are numbers", arg, ErrInvalidMapping)
+		}
+		userIDs[from] = to
+	}
+
+	return userIDs, nil
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseUserMap(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    map[string]string
+		wantErr error
+	}{
+		{args: nil, want: map[string]string{}},
+		{args: []string{"1=2", "3=4"}, want: map[string]string{"1": "2", "3": "4"}},
+		{args: []string{"1"}, wantErr: ErrInvalidMapping},
+		{args: []string{"=2"}, wantErr: ErrInvalidMapping},
+		{args: []string{"1="}, wantErr: ErrInvalidMapping},
+		{args: []string{"1=../2"}, wantErr: ErrInvalidMapping},
+		{args: []string{"a=2"}, wantErr: ErrInvalidMapping},
+	}
+
+	for _, tt := range tests {
+		got, err := parseUserMap(tt.args)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("parseUserMap(%q) error = %v, want %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseUserMap(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
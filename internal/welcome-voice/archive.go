@@ -0,0 +1,318 @@
+package welcomevoice
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	manifestName    = "manifest.json"
+	manifestVersion = 1
+	archiveSoundDir = "sounds"
+)
+
+// ErrUnsupportedArchive is returned for archives of an unknown version.
+var ErrUnsupportedArchive = errors.New("unsupported archive")
+
+type manifest struct {
+	Version    int              `json:"version"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Sounds     []*manifestSound `json:"sounds"`
+}
+
+type manifestSound struct {
+	UserID string `json:"user_id"`
+	// File is the path of the sound in the archive.
+	File      string    `json:"file"`
+	MessageID string    `json:"message_id,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// importedSound is an active sound which came from an archive, it has no
+// message in the channel.
+type importedSound struct {
+	MessageID  string    `json:"message_id,omitempty"`
+	Source     string    `json:"source,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ImportedAt time.Time `json:"imported_at"`
+}
+
+// Export writes the active sounds of all users to a zip archive with a
+// manifest describing them.
+func Export(ctx context.Context, config Config, logger *log.Logger, to string) error {
+	logger.SetPrefix("[Welcome Voice]: ")
+
+	w := &WelcomeVoice{
+		config: config,
+		logger: logger,
+	}
+
+	repo, err := w.readRepository()
+	if err != nil {
+		return fmt.Errorf("read repository: %w", err)
+	}
+	w.repository = repo
+
+	userIDs, err := w.storedUsers(config.VoiceDir)
+	if err != nil {
+		return fmt.Errorf("stored users: %w", err)
+	}
+
+	f, err := os.Create(to)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+
+	m := &manifest{
+		Version:    manifestVersion,
+		ExportedAt: time.Now(),
+	}
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("interrupted: %w", err)
+		}
+
+		s, err := w.exportSound(zw, userID)
+		if err != nil {
+			return fmt.Errorf("user %s: %w", userID, err)
+		}
+		m.Sounds = append(m.Sounds, s)
+	}
+
+	mw, err := zw.Create(manifestName)
+	if err != nil {
+		return fmt.Errorf("create manifest: %w", err)
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("close zip: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close: %w", err)
+	}
+
+	logger.Printf("export: %d sounds written to %s", len(m.Sounds), to)
+
+	return nil
+}
+
+func (w *WelcomeVoice) exportSound(zw *zip.Writer, userID string) (*manifestSound, error) {
+	p := w.pathSoundData(userID)
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+
+	s := &manifestSound{
+		UserID:    userID,
+		File:      path.Join(archiveSoundDir, userID+voiceExtension),
+		CreatedAt: info.ModTime(),
+	}
+	if versions := w.repository.History[userID]; len(versions) > 0 {
+		v := versions[len(versions)-1]
+		s.MessageID = v.MessageID
+		s.Source = v.Source
+		s.CreatedAt = v.CreatedAt
+	} else if imported, ok := w.repository.Imports[userID]; ok {
+		s.MessageID = imported.MessageID
+		s.Source = imported.Source
+		s.CreatedAt = imported.CreatedAt
+	} else if o, ok := w.repository.Originals[userID]; ok {
+		s.MessageID = o.MessageID
+		s.CreatedAt = o.StoredAt
+	}
+
+	src, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer src.Close()
+
+	// Ogg Opus is compressed already.
+	dst, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     s.File,
+		Method:   zip.Store,
+		Modified: s.CreatedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create: %w", err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		return nil, fmt.Errorf("copy: %w", err)
+	}
+
+	return s, nil
+}
+
+// Import loads the sounds of an archive made by Export. userIDs maps user
+// IDs of the archive to the ones to import as, users who already have a
+// sound are skipped. The bot must not be running meanwhile.
+func Import(ctx context.Context, config Config, logger *log.Logger, from string, userIDs map[string]string) error {
+	logger.SetPrefix("[Welcome Voice]: ")
+
+	w := &WelcomeVoice{
+		config: config,
+		logger: logger,
+		ctx:    ctx,
+	}
+
+	repo, err := w.readRepository()
+	if errors.Is(err, os.ErrNotExist) {
+		repo = newRepository()
+	} else if err != nil {
+		return fmt.Errorf("read repository: %w", err)
+	}
+	w.repository = repo
+
+	zr, err := zip.OpenReader(from)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer zr.Close()
+
+	m, err := readManifest(&zr.Reader)
+	if err != nil {
+		return fmt.Errorf("read manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(config.VoiceDir, os.ModePerm); err != nil {
+		return fmt.Errorf("mkdir voice dir: %w", err)
+	}
+
+	var (
+		imported, skipped int
+		errs              []error
+	)
+	for _, s := range m.Sounds {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, fmt.Errorf("interrupted: %w", err))
+			break
+		}
+
+		userID := s.UserID
+		if mapped, ok := userIDs[userID]; ok {
+			userID = mapped
+		}
+
+		// The ID becomes a file name, a crafted archive must not point
+		// outside the voice directory.
+		if userID == "" || strings.Trim(userID, "0123456789") != "" {
+			errs = append(errs, fmt.Errorf("user %q: %w", userID, ErrInvalidUserID))
+			continue
+		}
+
+		if _, err := os.Stat(w.pathSoundData(userID)); err == nil {
+			logger.Printf("import: user %s has a sound, skipped", userID)
+			skipped++
+			continue
+		}
+
+		if err := w.importSound(&zr.Reader, s, userID); err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", userID, err))
+			continue
+		}
+		imported++
+	}
+
+	if err := w.writeRepository(); err != nil {
+		errs = append(errs, fmt.Errorf("write repository: %w", err))
+	}
+
+	logger.Printf("import: %d sounds imported, %d skipped, %d failed", imported, skipped, len(errs))
+
+	return errors.Join(errs...)
+}
+
+func readManifest(zr *zip.Reader) (*manifest, error) {
+	f, err := zr.Open(manifestName)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	var m manifest
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if m.Version != manifestVersion {
+		return nil, fmt.Errorf("version %d: %w", m.Version, ErrUnsupportedArchive)
+	}
+
+	return &m, nil
+}
+
+func (w *WelcomeVoice) importSound(zr *zip.Reader, s *manifestSound, userID string) error {
+	src, err := zr.Open(s.File)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer src.Close()
+
+	to := w.pathSoundData(userID)
+	temp := to + ".tmp"
+
+	if err := func() error {
+		dst, err := os.Create(temp)
+		if err != nil {
+			return fmt.Errorf("create: %w", err)
+		}
+		defer dst.Close()
+
+		n, err := io.Copy(dst, io.LimitReader(src, voiceMaxSize+1))
+		if err != nil {
+			return fmt.Errorf("copy: %w", err)
+		}
+		if n > voiceMaxSize {
+			return ErrVoiceTooLarge
+		}
+
+		return dst.Close()
+	}(); err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+
+	if err := checkSound(temp); err != nil {
+		_ = os.Remove(temp)
+		return fmt.Errorf("check: %w", err)
+	}
+
+	if err := w.checkBanned(temp, nil); err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+
+	if err := os.Rename(temp, to); err != nil {
+		_ = os.Remove(temp)
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	w.repository.Imports[userID] = &importedSound{
+		MessageID:  s.MessageID,
+		Source:     s.Source,
+		CreatedAt:  s.CreatedAt,
+		ImportedAt: time.Now(),
+	}
+	w.removeFallback(userID)
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package welcomevoice
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path"
+	"testing"
+)
+
+// writeArchive packs the sounds with a manifest, every sound gets the file.
+func writeArchive(t *testing.T, sound string, sounds []*manifestSound) string {
+	t.Helper()
+
+	data, err := os.ReadFile(sound)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := path.Join(t.TempDir(), "sounds.zip")
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for _, s := range sounds {
+		s.File = path.Join(archiveSoundDir, s.UserID+voiceExtension)
+		w, err := zw.Create(s.File)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	w, err := zw.Create(manifestName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewEncoder(w).Encode(&manifest{Version: manifestVersion, Sounds: sounds}); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestImportRejectsInvalidUserIDs(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{})
+	archive := writeArchive(t, writeOpus(t, 312, []uint64{960}, nil), []*manifestSound{
+		{UserID: "../../x"},
+		{UserID: "1"},
+		{UserID: "2"},
+	})
+
+	err := Import(context.Background(), w.config, log.New(io.Discard, "", 0), archive, map[string]string{"2": "../y"})
+	if !errors.Is(err, ErrInvalidUserID) {
+		t.Fatalf("Import() error = %v, want %v", err, ErrInvalidUserID)
+	}
+
+	if _, err := os.Stat(w.pathSoundData("1")); err != nil {
+		t.Errorf("valid sound: %v", err)
+	}
+	for _, p := range []string{path.Join(w.config.VoiceDir, "../../x"+voiceExtension), path.Join(w.config.VoiceDir, "../y"+voiceExtension)} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("%s is written: %v", p, err)
+		}
+	}
+}
+
+func TestImportRejectsBanned(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{})
+	sound := writeOpus(t, 312, []uint64{960}, nil)
+
+	hash, err := hashFile(sound)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.repository.Banned = []*bannedSound{{Hashes: []string{hash}}}
+	w.saveRepository()
+
+	archive := writeArchive(t, sound, []*manifestSound{{UserID: "1"}})
+
+	var rejectErr *RejectError
+	if err := Import(context.Background(), w.config, log.New(io.Discard, "", 0), archive, nil); !errors.As(err, &rejectErr) {
+		t.Fatalf("Import() error = %v, want a rejection", err)
+	}
+	if _, err := os.Stat(w.pathSoundData("1")); !os.IsNotExist(err) {
+		t.Errorf("banned sound is imported: %v", err)
+	}
+}
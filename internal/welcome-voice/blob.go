@@ -0,0 +1,337 @@
+package welcomevoice
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// blobDir holds every distinct active sound once, named by its content
+// hash. The sounds of users are hard links to the blobs.
+const blobDir = "blobs"
+
+type bannedSound struct {
+	// Hashes are the converted sound and the original upload.
+	Hashes   []string  `json:"hashes"`
+	UserID   string    `json:"user_id"`
+	Reason   string    `json:"reason,omitempty"`
+	BannedAt time.Time `json:"banned_at"`
+}
+
+func (w *WelcomeVoice) pathBlobData(hash string) string {
+	return path.Join(w.config.VoiceDir, blobDir, hash+voiceExtension)
+}
+
+func hashFile(p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// dedupSound makes the user's active sound a link to the blob with the same
+// content, storing a new blob if there is none. The reference is dropped if
+// the user has no sound.
+func (w *WelcomeVoice) dedupSound(userID string) error {
+	p := w.pathSoundData(userID)
+
+	hash, err := hashFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		w.releaseSound(userID)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("hash: %w", err)
+	}
+
+	if old, ok := w.repository.Sounds[userID]; ok && old == hash {
+		return nil
+	}
+
+	blob := w.pathBlobData(hash)
+	if _, err := os.Stat(blob); errors.Is(err, os.ErrNotExist) {
+		if err := os.Link(p, blob); err != nil {
+			return fmt.Errorf("link blob: %w", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("stat blob: %w", err)
+	} else {
+		// The link is made next to the sound and renamed over it, so the
+		// sound is never missing.
+		temp := p + ".link"
+		_ = os.Remove(temp)
+		if err := os.Link(blob, temp); err != nil {
+			return fmt.Errorf("link sound: %w", err)
+		}
+		if err := os.Rename(temp, p); err != nil {
+			_ = os.Remove(temp)
+			return fmt.Errorf("rename: %w", err)
+		}
+	}
+
+	w.releaseSound(userID)
+	w.repository.Sounds[userID] = hash
+	w.saveRepository()
+
+	return nil
+}
+
+// releaseSound drops the user's reference, removing the blob if nobody
+// else uses it.
+func (w *WelcomeVoice) releaseSound(userID string) {
+	hash, ok := w.repository.Sounds[userID]
+	if !ok {
+		return
+	}
+	delete(w.repository.Sounds, userID)
+	w.saveRepository()
+
+	for _, other := range w.repository.Sounds {
+		if other == hash {
+			return
+		}
+	}
+
+	if err := os.Remove(w.pathBlobData(hash)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		w.logger.Printf("release sound: remove blob: %s", err.Error())
+	}
+}
+
+// dedupLibrary links all active sounds to blobs and removes the blobs nobody
+// refers to.
+func (w *WelcomeVoice) dedupLibrary() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	userIDs, err := w.storedUsers(w.config.VoiceDir)
+	if err != nil {
+		return fmt.Errorf("stored users: %w", err)
+	}
+	for userID := range w.repository.Sounds {
+		if !slices.Contains(userIDs, userID) {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	for _, userID := range userIDs {
+		if err := w.dedupSound(userID); err != nil {
+			w.logger.Printf("dedup: %s: %s", userID, err.Error())
+		}
+	}
+
+	entries, err := os.ReadDir(path.Join(w.config.VoiceDir, blobDir))
+	if err != nil {
+		return fmt.Errorf("read blob dir: %w", err)
+	}
+
+	used := make(map[string]bool, len(w.repository.Sounds))
+	for _, hash := range w.repository.Sounds {
+		used[hash] = true
+	}
+
+	var removed int
+	for _, e := range entries {
+		if used[strings.TrimSuffix(e.Name(), voiceExtension)] {
+			continue
+		}
+		if err := os.Remove(path.Join(w.config.VoiceDir, blobDir, e.Name())); err != nil {
+			w.logger.Printf("dedup: remove blob: %s", err.Error())
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		w.logger.Printf("dedup: removed %d unused blobs", removed)
+	}
+
+	return nil
+}
+
+func (w *WelcomeVoice) isBanned(hash string) bool {
+	return slices.IndexFunc(w.repository.Banned, func(b *bannedSound) bool {
+		return slices.Contains(b.Hashes, hash)
+	}) != -1
+}
+
+// checkBanned rejects a staged sound whose content or original upload is
+// banned.
+func (w *WelcomeVoice) checkBanned(staged string, original *originalSound) error {
+	if original != nil && w.isBanned(original.Hash) {
+		return reject("This sound is banned on the server.")
+	}
+
+	hash, err := hashFile(staged)
+	if err != nil {
+		return fmt.Errorf("hash: %w", err)
+	}
+	if w.isBanned(hash) {
+		return reject("This sound is banned on the server.")
+	}
+
+	return nil
+}
+
+// banSound bans the user's active sound and removes it from everyone who
+// uses it. It returns the number of users whose sound was removed.
+func (w *WelcomeVoice) banSound(userID, reason string) (int, error) {
+	hash, ok := w.repository.Sounds[userID]
+	if !ok {
+		return 0, reject("<@%s> has no sound.", userID)
+	}
+
+	ban := &bannedSound{
+		Hashes:   []string{hash},
+		UserID:   userID,
+		Reason:   reason,
+		BannedAt: time.Now(),
+	}
+	if o, ok := w.repository.Originals[userID]; ok && o.Hash != "" {
+		ban.Hashes = append(ban.Hashes, o.Hash)
+	}
+	w.repository.Banned = append(w.repository.Banned, ban)
+	w.saveRepository()
+
+	var removed int
+	for other, h := range w.repository.Sounds {
+		o := w.repository.Originals[other]
+		if h != hash && (o == nil || !slices.Contains(ban.Hashes, o.Hash)) {
+			continue
+		}
+
+		if err := w.removeBannedSound(other, reason); err != nil {
+			w.logger.Printf("ban: %s: %s", other, err.Error())
+			continue
+		}
+		removed++
+	}
+
+	w.removeBannedHistory(ban)
+
+	return removed, nil
+}
+
+// removeBannedHistory drops the history versions matching the ban, so they
+// can not be rolled back to.
+func (w *WelcomeVoice) removeBannedHistory(ban *bannedSound) {
+	for userID, versions := range w.repository.History {
+		kept := make([]*soundVersion, 0, len(versions))
+		for _, v := range versions {
+			hash, err := hashFile(w.pathHistoryData(userID, v.ID))
+			if err != nil || !slices.Contains(ban.Hashes, hash) {
+				kept = append(kept, v)
+				continue
+			}
+
+			if err := os.Remove(w.pathHistoryData(userID, v.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				w.logger.Printf("ban: remove history version: %s", err.Error())
+			}
+		}
+		w.repository.History[userID] = kept
+	}
+
+	w.saveRepository()
+}
+
+func (w *WelcomeVoice) removeBannedSound(userID, reason string) error {
+	if err := os.Remove(w.pathSoundData(userID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove: %w", err)
+	}
+	w.releaseSound(userID)
+	w.setOriginal(userID, nil)
+
+	if messageID, ok := w.messageByUser[userID]; ok {
+		if err := w.client.ChannelMessageDelete(w.config.ChannelID, messageID); err != nil {
+			w.logger.Printf("ban: remove message: %s", err.Error())
+		}
+		delete(w.messageByUser, userID)
+	}
+
+	notice := "Your welcome sound was banned by a moderator."
+	if reason != "" {
+		notice += " Reason: " + reason
+	}
+	if err := w.sendDirect(userID, notice); err != nil {
+		w.logger.Printf("ban: notify: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (w *WelcomeVoice) commandBan(_ *commandContext, args []string) (*discordgo.MessageSend, error) {
+	if len(args) < 1 {
+		return nil, reject("Whose sound should be banned?")
+	}
+
+	userID, err := parseUser(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	removed, err := w.banSound(userID, strings.Join(args[1:], " "))
+	if err != nil {
+		return nil, err
+	}
+
+	return textReply("Banned the sound of <@%s>, removed it from %d users.", userID, removed), nil
+}
+
+func (w *WelcomeVoice) commandBanned(_ *commandContext, _ []string) (*discordgo.MessageSend, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.repository.Banned) == 0 {
+		return textReply("No sounds are banned."), nil
+	}
+
+	var b strings.Builder
+	for i, ban := range w.repository.Banned {
+		fmt.Fprintf(&b, "%d. sound of <@%s>, <t:%d:R>", i+1, ban.UserID, ban.BannedAt.Unix())
+		if ban.Reason != "" {
+			fmt.Fprintf(&b, " — %s", ban.Reason)
+		}
+		b.WriteString("\n")
+	}
+
+	return textReply("%s", b.String()), nil
+}
+
+func (w *WelcomeVoice) commandUnban(_ *commandContext, args []string) (*discordgo.MessageSend, error) {
+	if len(args) < 1 {
+		return nil, reject("Which ban should be lifted? See `%s banned`.", w.config.CommandPrefix)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	number, err := strconv.Atoi(args[0])
+	if err != nil || number < 1 || number > len(w.repository.Banned) {
+		return nil, reject("There is no ban number %s.", args[0])
+	}
+
+	ban := w.repository.Banned[number-1]
+	w.repository.Banned = slices.Delete(w.repository.Banned, number-1, number)
+	w.saveRepository()
+
+	return textReply("Lifted the ban of the sound of <@%s>.", ban.UserID), nil
+}
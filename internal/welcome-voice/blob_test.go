@@ -0,0 +1,43 @@
+package welcomevoice
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestRemoveBannedHistory(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{})
+
+	versions := map[string]string{"v1": "banned", "v2": "fine"}
+	for _, userID := range []string{"1", "2"} {
+		for id, data := range versions {
+			w.repository.History[userID] = append(w.repository.History[userID], &soundVersion{ID: id, CreatedAt: time.Now()})
+
+			p := w.pathHistoryData(userID, id)
+			if err := os.MkdirAll(path.Dir(p), os.ModePerm); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	hash, err := hashFile(w.pathHistoryData("1", "v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.removeBannedHistory(&bannedSound{Hashes: []string{hash}})
+
+	for _, userID := range []string{"1", "2"} {
+		kept := w.repository.History[userID]
+		if len(kept) != 1 || kept[0].ID != "v2" {
+			t.Errorf("history of %s = %v, want only v2", userID, kept)
+		}
+		if _, err := os.Stat(w.pathHistoryData(userID, "v1")); !os.IsNotExist(err) {
+			t.Errorf("banned version of %s is kept: %v", userID, err)
+		}
+	}
+}
@@ -0,0 +1,489 @@
+package welcomevoice
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+const slashCommandName = "mog"
+
+var (
+	minNumber = 1.0
+
+	switchOptions = []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "state",
+			Description: "on or off",
+			Required:    true,
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "on", Value: "on"},
+				{Name: "off", Value: "off"},
+			},
+		},
+	}
+)
+
+var mentionRegexp = regexp.MustCompile(`^<@!?(\d+)>$|^(\d+)$`)
+
+// commandContext is the invocation of a command.
+type commandContext struct {
+	GuildID   string
+	ChannelID string
+	Author    *discordgo.User
+	Member    *discordgo.Member
+}
+
+type command struct {
+	Name        string
+	Usage       string
+	Description string
+	Moderator   bool
+	// Ephemeral replies to slash commands are only shown to the caller.
+	Ephemeral bool
+	// Options describe the arguments of the slash command, in the order
+	// they are passed to Run.
+	Options []*discordgo.ApplicationCommandOption
+	Run     func(c *commandContext, args []string) (*discordgo.MessageSend, error)
+}
+
+func (w *WelcomeVoice) commands() []command {
+	commands := []command{
+		{
+			Name:        "help",
+			Description: "List commands",
+			Run:         w.commandHelp,
+		},
+	}
+
+	commands = append(commands, command{
+		Name:        "info",
+		Description: "Show and send your current welcome sound",
+		Ephemeral:   true,
+		Run:         w.commandInfo,
+	})
+
+	commands = append(commands, command{
+		Name:        "stats",
+		Usage:       "[user]",
+		Description: "Show the most played greetings or a user's stats",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Name:        "user",
+				Description: "Whose stats to show",
+			},
+		},
+		Run: w.commandStats,
+	})
+
+	commands = append(commands,
+		command{
+			Name:        "listen",
+			Usage:       "on|off",
+			Description: "Hear greetings of others or skip them",
+			Ephemeral:   true,
+			Options:     switchOptions,
+			Run:         w.commandListen,
+		},
+		command{
+			Name:        "greet",
+			Usage:       "on|off",
+			Description: "Play your own greeting when you join or not",
+			Ephemeral:   true,
+			Options:     switchOptions,
+			Run:         w.commandGreet,
+		},
+		command{
+			Name:        "mute",
+			Usage:       "[user]",
+			Description: "Stop hearing the user's greeting, or list muted users",
+			Ephemeral:   true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Whose greeting to mute",
+				},
+			},
+			Run: w.commandMute,
+		},
+		command{
+			Name:        "unmute",
+			Usage:       "<user>",
+			Description: "Hear the user's greeting again",
+			Ephemeral:   true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Whose greeting to unmute",
+					Required:    true,
+				},
+			},
+			Run: w.commandUnmute,
+		},
+	)
+
+	if w.historySize() > 0 {
+		commands = append(commands, command{
+			Name:        "history",
+			Usage:       "[preview|rollback <number>]",
+			Description: "List, preview or restore your previous sounds",
+			Ephemeral:   true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "action",
+					Description: "What to do with the version",
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "preview", Value: "preview"},
+						{Name: "rollback", Value: "rollback"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "number",
+					Description: "Version number from the list",
+					MinValue:    &minNumber,
+				},
+			},
+			Run: w.commandHistory,
+		})
+	}
+
+	if w.config.Soundboard.Enabled {
+		commands = append(commands,
+			command{
+				Name:        "play",
+				Usage:       "[user]",
+				Description: "Play your or another user's sound in your voice channel",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "Whose sound to play, yours by default",
+					},
+				},
+				Run: w.commandPlay,
+			},
+			command{
+				Name:        "random",
+				Description: "Play a random sound in your voice channel",
+				Run:         w.commandRandom,
+			},
+		)
+	}
+
+	// Bans and the spam counters work without the approval queue, the
+	// moderator roles are enough.
+	commands = append(commands,
+		command{
+			Name:        "spam",
+			Description: "Show skipped greetings and disabled users",
+			Moderator:   true,
+			Run:         w.commandSpam,
+		},
+		command{
+			Name:        "ban",
+			Usage:       "<user> [reason]",
+			Description: "Ban the user's sound for everyone and remove it",
+			Moderator:   true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Whose sound to ban",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "reason",
+					Description: "Told to the users",
+				},
+			},
+			Run: w.commandBan,
+		},
+		command{
+			Name:        "banned",
+			Description: "List banned sounds",
+			Moderator:   true,
+			Run:         w.commandBanned,
+		},
+		command{
+			Name:        "unban",
+			Usage:       "<number>",
+			Description: "Lift a ban from the list",
+			Moderator:   true,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "number",
+					Description: "Ban number from the list",
+					Required:    true,
+					MinValue:    &minNumber,
+				},
+			},
+			Run: w.commandUnban,
+		},
+	)
+
+	if w.config.Moderation.Enabled {
+		commands = append(commands,
+			command{
+				Name:        "pending",
+				Description: "List sounds waiting for approval",
+				Moderator:   true,
+				Run:         w.commandPending,
+			},
+			command{
+				Name:        "approve",
+				Usage:       "<user>",
+				Description: "Approve the user's pending sound",
+				Moderator:   true,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "Uploader of the sound",
+						Required:    true,
+					},
+				},
+				Run: w.commandApprove,
+			},
+			command{
+				Name:        "reject",
+				Usage:       "<user> [reason]",
+				Description: "Reject the user's pending sound",
+				Moderator:   true,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "Uploader of the sound",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "reason",
+						Description: "Told to the uploader",
+					},
+				},
+				Run: w.commandReject,
+			},
+		)
+	}
+
+	return commands
+}
+
+// isCommand reports whether the message is the prefix alone or followed by
+// whitespace, "!mogger" is not a command.
+func (w *WelcomeVoice) isCommand(content string) bool {
+	rest, ok := strings.CutPrefix(content, w.config.CommandPrefix)
+	if w.config.CommandPrefix == "" || !ok {
+		return false
+	}
+
+	r, _ := utf8.DecodeRuneInString(rest)
+
+	return rest == "" || unicode.IsSpace(r)
+}
+
+func (w *WelcomeVoice) onCommand(_ *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author.ID == w.client.State.User.ID || !w.isCommand(m.Content) {
+		return
+	}
+
+	args := strings.Fields(strings.TrimPrefix(m.Content, w.config.CommandPrefix))
+	if len(args) == 0 {
+		args = []string{"help"}
+	}
+
+	reply, err := w.runCommand(w.commands(), &commandContext{
+		GuildID:   m.GuildID,
+		ChannelID: m.ChannelID,
+		Author:    m.Author,
+		Member:    m.Member,
+	}, args[0], args[1:])
+	if err != nil {
+		w.logger.Printf("command %s: %s", args[0], err.Error())
+		reply = textReply("Something went wrong.")
+	}
+	reply.Reference = m.Reference()
+
+	if _, err := w.client.ChannelMessageSendComplex(m.ChannelID, reply); err != nil {
+		w.logger.Printf("command %s: reply: %s", args[0], err.Error())
+	}
+}
+
+// registerSlashCommands publishes all commands as subcommands of one slash
+// command, replacing the previously registered ones.
+func (w *WelcomeVoice) registerSlashCommands() error {
+	root := &discordgo.ApplicationCommand{
+		Name:        slashCommandName,
+		Description: "Welcome sounds",
+	}
+
+	for _, cmd := range w.commands() {
+		root.Options = append(root.Options, &discordgo.ApplicationCommandOption{
+			Type:        discordgo.ApplicationCommandOptionSubCommand,
+			Name:        cmd.Name,
+			Description: cmd.Description,
+			Options:     cmd.Options,
+		})
+	}
+
+	if _, err := w.client.ApplicationCommandBulkOverwrite(w.client.State.User.ID, "", []*discordgo.ApplicationCommand{root}); err != nil {
+		return fmt.Errorf("bulk overwrite: %w", err)
+	}
+
+	return nil
+}
+
+func (w *WelcomeVoice) onInteraction(_ *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+
+	data := i.ApplicationCommandData()
+	if data.Name != slashCommandName || len(data.Options) == 0 {
+		return
+	}
+	sub := data.Options[0]
+
+	c := &commandContext{
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		Member:    i.Member,
+		Author:    i.User,
+	}
+	if i.Member != nil {
+		c.Author = i.Member.User
+	}
+
+	commands := w.commands()
+	cmd, _ := findCommand(commands, sub.Name)
+
+	// Commands may convert or hash sounds, which takes longer than an
+	// interaction may wait for the response, the reply is edited in later.
+	deferred := &discordgo.InteractionResponseData{}
+	if cmd.Ephemeral {
+		deferred.Flags = discordgo.MessageFlagsEphemeral
+	}
+	if err := w.client.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: deferred,
+	}); err != nil {
+		w.logger.Printf("slash command %s: defer: %s", sub.Name, err.Error())
+		return
+	}
+
+	reply, err := w.runCommand(commands, c, sub.Name, slashArgs(cmd, sub))
+	if err != nil {
+		w.logger.Printf("slash command %s: %s", sub.Name, err.Error())
+		reply = textReply("Something went wrong.")
+	}
+
+	if _, err := w.client.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &reply.Content,
+		Files:   reply.Files,
+	}); err != nil {
+		w.logger.Printf("slash command %s: respond: %s", sub.Name, err.Error())
+	}
+}
+
+// slashArgs converts the options of the subcommand to the arguments of the
+// text command, following the order of the command's options.
+func slashArgs(cmd command, sub *discordgo.ApplicationCommandInteractionDataOption) []string {
+	var args []string
+	for _, def := range cmd.Options {
+		j := slices.IndexFunc(sub.Options, func(o *discordgo.ApplicationCommandInteractionDataOption) bool {
+			return o.Name == def.Name
+		})
+		if j == -1 {
+			break
+		}
+
+		switch o := sub.Options[j]; o.Type {
+		case discordgo.ApplicationCommandOptionInteger:
+			args = append(args, strconv.FormatInt(o.IntValue(), 10))
+		default:
+			args = append(args, fmt.Sprint(o.Value))
+		}
+	}
+
+	return args
+}
+
+// runCommand executes the command. Errors caused by the input are returned as
+// the reply, other errors are left to the caller.
+func (w *WelcomeVoice) runCommand(commands []command, c *commandContext, name string, args []string) (*discordgo.MessageSend, error) {
+	cmd, ok := findCommand(commands, name)
+	if !ok {
+		return textReply("Unknown command `%s`, see `%s help`.", name, w.config.CommandPrefix), nil
+	}
+
+	if cmd.Moderator && !w.isModerator(c.Member) {
+		return textReply("Only moderators can use this command."), nil
+	}
+
+	reply, err := cmd.Run(c, args)
+
+	var rejectErr *RejectError
+	if errors.As(err, &rejectErr) {
+		return textReply("%s", rejectErr.Reason), nil
+	}
+
+	return reply, err
+}
+
+func findCommand(commands []command, name string) (command, bool) {
+	i := slices.IndexFunc(commands, func(cmd command) bool { return cmd.Name == name })
+	if i == -1 {
+		return command{}, false
+	}
+
+	return commands[i], true
+}
+
+func (w *WelcomeVoice) commandHelp(_ *commandContext, _ []string) (*discordgo.MessageSend, error) {
+	var b strings.Builder
+	for _, cmd := range w.commands() {
+		fmt.Fprintf(&b, "`%s %s", w.config.CommandPrefix, cmd.Name)
+		if cmd.Usage != "" {
+			fmt.Fprintf(&b, " %s", cmd.Usage)
+		}
+		fmt.Fprintf(&b, "` — %s\n", cmd.Description)
+	}
+
+	return textReply("%s", b.String()), nil
+}
+
+func textReply(format string, a ...any) *discordgo.MessageSend {
+	return &discordgo.MessageSend{
+		Content: fmt.Sprintf(format, a...),
+	}
+}
+
+// parseUser accepts a user mention or a raw user ID.
+func parseUser(arg string) (string, error) {
+	m := mentionRegexp.FindStringSubmatch(arg)
+	if m == nil {
+		return "", reject("`%s` is not a user.", arg)
+	}
+
+	if m[1] != "" {
+		return m[1], nil
+	}
+
+	return m[2], nil
+}
@@ -0,0 +1,31 @@
+package welcomevoice
+
+import "testing"
+
+func TestIsCommand(t *testing.T) {
+	w := &WelcomeVoice{config: Config{CommandPrefix: "!mog"}}
+
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{content: "!mog", want: true},
+		{content: "!mog help", want: true},
+		{content: "!mog\thelp", want: true},
+		{content: "!mog\nhelp", want: true},
+		{content: "!mogger"},
+		{content: "mog help"},
+		{content: ""},
+	}
+
+	for _, tt := range tests {
+		if got := w.isCommand(tt.content); got != tt.want {
+			t.Errorf("isCommand(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+
+	w.config.CommandPrefix = ""
+	if w.isCommand("help") {
+		t.Error("isCommand() without a prefix = true, want false")
+	}
+}
@@ -5,8 +5,28 @@ import (
 )
 
 type Config struct {
-	ChannelID     string            `json:"channel_id,omitempty"`
-	VoiceDir      string            `json:"voice_dir,omitempty"`
-	VoiceDuration duration.Duration `json:"voice_duration,omitempty"`
-	Emoji         string            `json:"emoji,omitempty"`
+	ChannelID             string                 `json:"channel_id,omitempty"`
+	VoiceDir              string                 `json:"voice_dir,omitempty"`
+	VoiceDuration         duration.Duration      `json:"voice_duration,omitempty"`
+	Emoji                 string                 `json:"emoji,omitempty"`
+	DownloadTimeout       duration.Duration      `json:"download_timeout,omitempty"`
+	Convert               ConvertConfig          `json:"convert,omitempty"`
+	Random                []RandomProviderConfig `json:"random,omitempty"`
+	FallbackPolicy        string                 `json:"fallback_policy,omitempty"`
+	FallbackNotify        bool                   `json:"fallback_notify,omitempty"`
+	CommandPrefix         string                 `json:"command_prefix,omitempty"`
+	HistorySize           *int                   `json:"history_size,omitempty"`
+	OriginalQuotaMB       int64                  `json:"original_quota_mb,omitempty"`
+	VerifyInterval        duration.Duration      `json:"verify_interval,omitempty"`
+	VerifyReportChannelID string                 `json:"verify_report_channel_id,omitempty"`
+	Moderation            ModerationConfig       `json:"moderation,omitempty"`
+	Soundboard            SoundboardConfig       `json:"soundboard,omitempty"`
+	SlashCommands         bool                   `json:"slash_commands,omitempty"`
+	Cooldown              CooldownConfig         `json:"cooldown,omitempty"`
+	Schedule              ScheduleConfig         `json:"schedule,omitempty"`
+	Presence              PresenceConfig         `json:"presence,omitempty"`
+	TTS                   TTSConfig              `json:"tts,omitempty"`
+	Defaults              DefaultsConfig         `json:"defaults,omitempty"`
+	DisablePreview        bool                   `json:"disable_preview,omitempty"`
+	Mix                   MixConfig              `json:"mix,omitempty"`
 }
@@ -0,0 +1,273 @@
+package welcomevoice
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tekig/mog-go/internal/duration"
+)
+
+type ConvertConfig struct {
+	// Loudness is the EBU R128 integrated loudness target in LUFS.
+	Loudness float64 `json:"loudness,omitempty"`
+	// TruePeak is the maximum true peak in dBTP.
+	TruePeak float64 `json:"true_peak,omitempty"`
+	// LoudnessRange is the loudness range target in LU.
+	LoudnessRange float64 `json:"loudness_range,omitempty"`
+	// SilenceThreshold is the level in dB below which leading and trailing
+	// audio is considered silence and removed.
+	SilenceThreshold float64 `json:"silence_threshold,omitempty"`
+	// DisableNormalize turns off loudness normalization.
+	DisableNormalize bool `json:"disable_normalize,omitempty"`
+	// DisableTrimSilence turns off leading and trailing silence removal.
+	DisableTrimSilence bool `json:"disable_trim_silence,omitempty"`
+	// DisablePassthrough makes every upload go through ffmpeg, even Ogg Opus
+	// files which could be stored as is. Passthrough only happens with both
+	// normalization and silence trimming disabled, as it skips them.
+	DisablePassthrough bool `json:"disable_passthrough,omitempty"`
+	// Workers is the number of conversions run at once, the number of CPUs
+	// if zero.
+	Workers int `json:"workers,omitempty"`
+	// QueueSize is the number of uploads which may wait for a worker.
+	QueueSize int `json:"queue_size,omitempty"`
+	// Timeout cancels a download and conversion which takes longer.
+	Timeout duration.Duration `json:"timeout,omitempty"`
+}
+
+func (w *WelcomeVoice) convertSound(ctx context.Context, from, to string, trim trimRange) error {
+	if w.canPassthrough(from, trim) {
+		if err := copyFile(from, to); err != nil {
+			return fmt.Errorf("copy: %w", err)
+		}
+		w.logger.Printf("to=%s: stored without transcoding", to)
+
+		return nil
+	}
+
+	// The result is written next to the target and renamed, so a failed
+	// conversion never breaks the sound which is already there.
+	temp, err := tempNear(to)
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", w.convertArgs(from, temp, trim)...)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		_ = os.Remove(temp)
+		return fmt.Errorf("ffmpeg: %s, %w", string(output), err)
+	}
+	w.logger.Printf("to=%s: %s", to, string(output))
+
+	if err := os.Rename(temp, to); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+// canPassthrough reports whether the file can be stored as is: it is
+// already an Ogg Opus stream playable by Discord, needs no trimming and no
+// filters are configured.
+func (w *WelcomeVoice) canPassthrough(from string, trim trimRange) bool {
+	c := w.config.Convert
+	if c.DisablePassthrough || !c.DisableNormalize || !c.DisableTrimSilence || !trim.IsZero() {
+		return false
+	}
+
+	info, err := inspectOgg(from)
+	if err != nil || info.SampleRate != opusSampleRate {
+		return false
+	}
+
+	if max := w.config.VoiceDuration.Duration; max > 0 && info.Duration > max {
+		return false
+	}
+
+	return true
+}
+
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return fmt.Errorf("open: %w", err)
+	}
+	defer src.Close()
+
+	temp, err := tempNear(to)
+	if err != nil {
+		return err
+	}
+
+	if err := func() error {
+		dst, err := os.OpenFile(temp, os.O_WRONLY|os.O_TRUNC, 0)
+		if err != nil {
+			return fmt.Errorf("open: %w", err)
+		}
+		defer dst.Close()
+
+		if _, err := io.Copy(dst, src); err != nil {
+			return fmt.Errorf("copy: %w", err)
+		}
+
+		return dst.Close()
+	}(); err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+
+	if err := os.Rename(temp, to); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	return nil
+}
+
+// tempNear creates an empty file next to the target to be renamed over it.
+// Every call gets its own name, the same cache file may be prepared by
+// several joins at once.
+func tempNear(to string) (string, error) {
+	f, err := os.CreateTemp(path.Dir(to), strings.TrimSuffix(path.Base(to), voiceExtension)+".*.tmp"+voiceExtension)
+	if err != nil {
+		return "", fmt.Errorf("create temp: %w", err)
+	}
+	_ = f.Close()
+
+	return f.Name(), nil
+}
+
+// withConvertTimeout bounds ffmpeg and downloads run outside the worker pool
+// by the conversion timeout.
+func (w *WelcomeVoice) withConvertTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := w.config.Convert.Timeout.Duration; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+func (w *WelcomeVoice) convertArgs(from, to string, trim trimRange) []string {
+	args := []string{"-y", "-vn"}
+
+	if trim.Start > 0 {
+		args = append(args, "-ss", formatSeconds(trim.Start.Seconds()))
+	}
+	if trim.Length > 0 {
+		args = append(args, "-t", formatSeconds(trim.Length.Seconds()))
+	}
+
+	args = append(args, "-i", from)
+
+	if filters := w.convertFilters(); len(filters) > 0 {
+		args = append(args, "-af", strings.Join(filters, ","))
+	}
+
+	if d := w.config.VoiceDuration.Duration; d > 0 {
+		args = append(args, "-t", formatSeconds(d.Seconds()))
+	}
+
+	// Bit exact output is the same for the same clip, so it is stored once.
+	return append(args, "-ar", "48000", "-c:a", "libopus", "-page_duration", "20000", "-fflags", "+bitexact", to)
+}
+
+func (w *WelcomeVoice) convertFilters() []string {
+	c := w.config.Convert
+
+	var filters []string
+
+	if !c.DisableTrimSilence {
+		// silenceremove only trims the beginning of the stream reliably, so
+		// trailing silence is removed by trimming the reversed stream.
+		trim := "silenceremove=start_periods=1:start_threshold=" + formatFloat(c.SilenceThreshold) + "dB"
+		filters = append(filters, trim, "areverse", trim, "areverse")
+	}
+
+	if !c.DisableNormalize {
+		filters = append(filters, fmt.Sprintf(
+			"loudnorm=I=%s:TP=%s:LRA=%s",
+			formatFloat(c.Loudness), formatFloat(c.TruePeak), formatFloat(c.LoudnessRange),
+		))
+	}
+
+	return filters
+}
+
+func formatSeconds(v float64) string {
+	return strconv.FormatFloat(v, 'f', 3, 64)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// scaleVolume writes a copy of the sound with the volume multiplied by the
+// factor to a temporary file. The caller removes it.
+func scaleVolume(ctx context.Context, from string, volume float64) (string, error) {
+	f, err := os.CreateTemp("", "mog-*"+voiceExtension)
+	if err != nil {
+		return "", fmt.Errorf("create temp: %w", err)
+	}
+	_ = f.Close()
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-y", "-i", from, "-af", "volume="+formatFloat(volume),
+		"-c:a", "libopus", "-page_duration", "20000", f.Name())
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("ffmpeg: %s, %w", string(output), err)
+	}
+
+	return f.Name(), nil
+}
+
+// measureLoudness returns the integrated loudness of the file in LUFS.
+func measureLoudness(ctx context.Context, path string) (float64, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-nostats", "-i", path, "-af", "ebur128=framelog=quiet", "-f", "null", "-")
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return 0, fmt.Errorf("ffmpeg: %s, %w", string(output), err)
+	}
+
+	// The summary is printed last, its "I:" line holds the integrated
+	// loudness: "    I:         -16.1 LUFS".
+	lines := strings.Split(string(output), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		fields := strings.Fields(lines[i])
+		if len(fields) == 3 && fields[0] == "I:" && fields[2] == "LUFS" {
+			v, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				return 0, fmt.Errorf("parse loudness: %w", err)
+			}
+
+			return v, nil
+		}
+	}
+
+	return 0, fmt.Errorf("loudness not found in ffmpeg output")
+}
+
+// probeDuration returns the length of the media file.
+func probeDuration(ctx context.Context, path string) (time.Duration, error) {
+	cmd := exec.CommandContext(ctx, "ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "default=noprint_wrappers=1:nokey=1", path)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe: %w", err)
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse duration: %w", err)
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
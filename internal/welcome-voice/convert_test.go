@@ -0,0 +1,144 @@
+package welcomevoice
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tekig/mog-go/internal/duration"
+	"golang.org/x/exp/slices"
+)
+
+func TestCanPassthrough(t *testing.T) {
+	sound := writeOpus(t, 312, []uint64{960, 1920}, nil)
+
+	tests := []struct {
+		name    string
+		convert ConvertConfig
+		trim    trimRange
+		want    bool
+	}{
+		{name: "filters enabled", convert: ConvertConfig{}},
+		{name: "only normalization disabled", convert: ConvertConfig{DisableNormalize: true}},
+		{name: "only silence trimming disabled", convert: ConvertConfig{DisableTrimSilence: true}},
+		{name: "filters disabled", convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true}, want: true},
+		{name: "passthrough disabled", convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true, DisablePassthrough: true}},
+		{name: "trimmed", convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true}, trim: trimRange{Start: time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &WelcomeVoice{config: Config{Convert: tt.convert}}
+			if got := w.canPassthrough(sound, tt.trim); got != tt.want {
+				t.Errorf("canPassthrough() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// flagValues returns the values given to the flag, in order.
+func flagValues(args []string, flag string) []string {
+	var values []string
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag {
+			values = append(values, args[i+1])
+		}
+	}
+
+	return values
+}
+
+func TestConvertFilters(t *testing.T) {
+	tests := []struct {
+		name          string
+		convert       ConvertConfig
+		wantSilence   bool
+		wantNormalize bool
+	}{
+		{name: "all filters", wantSilence: true, wantNormalize: true},
+		{name: "no normalization", convert: ConvertConfig{DisableNormalize: true}, wantSilence: true},
+		{name: "no silence trimming", convert: ConvertConfig{DisableTrimSilence: true}, wantNormalize: true},
+		{name: "no filters", convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.convert.Loudness = -16
+			tt.convert.TruePeak = -1.5
+			tt.convert.LoudnessRange = 11
+			tt.convert.SilenceThreshold = -50
+			w := &WelcomeVoice{config: Config{Convert: tt.convert}}
+
+			filters := w.convertFilters()
+
+			silence := slices.Contains(filters, "silenceremove=start_periods=1:start_threshold=-50dB")
+			if silence != tt.wantSilence {
+				t.Errorf("silence trimming = %v, want %v: %q", silence, tt.wantSilence, filters)
+			}
+			// Trailing silence is trimmed on the reversed stream, which is
+			// reversed back afterwards.
+			if reversed := strings.Count(strings.Join(filters, ","), "areverse"); tt.wantSilence && reversed != 2 {
+				t.Errorf("got %d areverse filters, want 2: %q", reversed, filters)
+			}
+
+			normalize := slices.Contains(filters, "loudnorm=I=-16:TP=-1.5:LRA=11")
+			if normalize != tt.wantNormalize {
+				t.Errorf("normalization = %v, want %v: %q", normalize, tt.wantNormalize, filters)
+			}
+		})
+	}
+}
+
+func TestConvertArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		convert   ConvertConfig
+		voice     time.Duration
+		trim      trimRange
+		wantStart []string
+		wantTime  []string
+		wantAF    bool
+	}{
+		{name: "plain", convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true}},
+		{name: "filters", wantAF: true},
+		{name: "length cap", convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true}, voice: 10 * time.Second, wantTime: []string{"10.000"}},
+		{name: "start", convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true}, trim: trimRange{Start: 1500 * time.Millisecond}, wantStart: []string{"1.500"}},
+		{name: "range", convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true}, trim: trimRange{Start: time.Second, Length: 2 * time.Second}, wantStart: []string{"1.000"}, wantTime: []string{"2.000"}},
+		{name: "range and cap", voice: 10 * time.Second, trim: trimRange{Length: 2 * time.Second}, wantTime: []string{"2.000", "10.000"}, wantAF: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &WelcomeVoice{config: Config{
+				Convert:       tt.convert,
+				VoiceDuration: duration.Duration{Duration: tt.voice},
+			}}
+
+			args := w.convertArgs("in.mp3", "out.ogg", tt.trim)
+
+			if got := flagValues(args, "-ss"); !slices.Equal(got, tt.wantStart) {
+				t.Errorf("-ss = %q, want %q", got, tt.wantStart)
+			}
+			if got := flagValues(args, "-t"); !slices.Equal(got, tt.wantTime) {
+				t.Errorf("-t = %q, want %q", got, tt.wantTime)
+			}
+			if got := len(flagValues(args, "-af")) > 0; got != tt.wantAF {
+				t.Errorf("-af given = %v, want %v", got, tt.wantAF)
+			}
+
+			// The range is applied to the input, the cap to the output.
+			input := slices.Index(args, "-i")
+			for i, arg := range args {
+				if arg == "-ss" && i > input {
+					t.Errorf("-ss after the input: %q", args)
+				}
+			}
+			if tt.trim.Length > 0 && slices.Index(args, "-t") > input {
+				t.Errorf("range -t after the input: %q", args)
+			}
+			if args[len(args)-1] != "out.ogg" {
+				t.Errorf("output = %s, want out.ogg", args[len(args)-1])
+			}
+		})
+	}
+}
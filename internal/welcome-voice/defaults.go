@@ -0,0 +1,85 @@
+package welcomevoice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+const defaultsDir = "defaults"
+
+type DefaultsConfig struct {
+	// Roles are tried in order, the first role the member has wins.
+	Roles []RoleSoundConfig `json:"roles,omitempty"`
+	// Bots is played for bot accounts.
+	Bots string `json:"bots,omitempty"`
+	// Guilds maps a guild ID to the sound played for everyone else.
+	Guilds map[string]string `json:"guilds,omitempty"`
+}
+
+type RoleSoundConfig struct {
+	RoleID string `json:"role_id,omitempty"`
+	Sound  string `json:"sound,omitempty"`
+}
+
+// defaultSound returns the configured sound for the member: a role sound,
+// the bot sound or the guild sound, in that order, and which of them it is.
+// An empty path means none is configured.
+func (w *WelcomeVoice) defaultSound(userID, guildID string, member *discordgo.Member) (string, string) {
+	c := w.config.Defaults
+
+	if member == nil && guildID != "" {
+		member, _ = w.client.State.Member(guildID, userID)
+	}
+
+	if member != nil {
+		for _, r := range c.Roles {
+			if slices.Contains(member.Roles, r.RoleID) {
+				return r.Sound, "role default"
+			}
+		}
+
+		if c.Bots != "" && member.User != nil && member.User.Bot {
+			return c.Bots, "bot default"
+		}
+	}
+
+	return c.Guilds[guildID], "guild default"
+}
+
+// prepareDefault converts a configured sound once within the conversion
+// timeout, the result is cached until the file changes. It runs without the
+// lock.
+func (w *WelcomeVoice) prepareDefault(ctx context.Context, sound string) (string, error) {
+	stat, err := os.Stat(sound)
+	if err != nil {
+		return "", fmt.Errorf("stat: %w", err)
+	}
+
+	key := sound + "\x00" + strconv.FormatInt(stat.Size(), 10) + "\x00" + strconv.FormatInt(stat.ModTime().UnixNano(), 10)
+	sum := sha256.Sum256([]byte(key))
+	cached := path.Join(w.config.VoiceDir, defaultsDir, hex.EncodeToString(sum[:16])+voiceExtension)
+
+	if _, err := os.Stat(cached); err == nil {
+		return cached, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("stat cache: %w", err)
+	}
+
+	ctx, cancel := w.withConvertTimeout(ctx)
+	defer cancel()
+
+	if err := w.convertSound(ctx, sound, cached, trimRange{}); err != nil {
+		return "", fmt.Errorf("convert sound: %w", err)
+	}
+
+	return cached, nil
+}
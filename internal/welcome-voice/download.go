@@ -0,0 +1,171 @@
+package welcomevoice
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"syscall"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+var urlRegexp = regexp.MustCompile(`<?(https?://[^\s<>]+)>?`)
+
+// soundSource returns the URL of the sound posted in the message and the
+// message content without that URL.
+func soundSource(m *discordgo.Message) (string, string, error) {
+	switch len(m.Attachments) {
+	case 0:
+	case 1:
+		attach := m.Attachments[0]
+		if attach.Size > voiceMaxSize {
+			return "", "", ErrVoiceTooLarge
+		}
+
+		return attach.ProxyURL, m.Content, nil
+	default:
+		return "", "", ErrAttachmentsNotFound
+	}
+
+	links := urlRegexp.FindAllStringSubmatchIndex(m.Content, -1)
+	if len(links) != 1 {
+		return "", "", ErrAttachmentsNotFound
+	}
+	link := links[0]
+
+	uri := m.Content[link[2]:link[3]]
+	if u, err := url.ParseRequestURI(uri); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", reject("Invalid link `%s`.", uri)
+	}
+
+	return uri, m.Content[:link[0]] + " " + m.Content[link[1]:], nil
+}
+
+// sourceName describes where the message's sound came from.
+func sourceName(m *discordgo.Message) string {
+	if len(m.Attachments) > 0 {
+		return m.Attachments[0].Filename
+	}
+
+	return urlRegexp.FindString(m.Content)
+}
+
+// newUploadClient returns the client for links posted by users. It only
+// speaks http and https and never connects to loopback, private or
+// link-local addresses, so users can not make the bot reach internal
+// services.
+func newUploadClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: refuseInternal,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialed instead of the target, which hides it from
+	// the check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to %s: %w", req.URL.Scheme, ErrForbiddenAddress)
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+
+			return nil
+		},
+	}
+}
+
+// refuseInternal is a dialer control which runs after name resolution, so
+// host names pointing to internal addresses are refused as well.
+func refuseInternal(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("split address: %w", err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || internalIP(ip) {
+		return fmt.Errorf("%s: %w", host, ErrForbiddenAddress)
+	}
+
+	return nil
+}
+
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+func (w *WelcomeVoice) downloadSound(ctx context.Context, uri string) (string, error) {
+	return download(ctx, w.httpClient, uri)
+}
+
+// download saves the sound to a temporary file. The caller removes it.
+func download(ctx context.Context, client *http.Client, uri string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return "", fmt.Errorf("new request: %w", err)
+	}
+
+	r, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("get: %w", err)
+	}
+	defer r.Body.Close()
+
+	if r.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("get %s: %w", r.Status, ErrUnexpectedStatus)
+	}
+
+	if r.ContentLength > voiceMaxSize {
+		return "", ErrVoiceTooLarge
+	}
+
+	body := bufio.NewReader(r.Body)
+
+	// Peek error is ignored, short files are sniffed by what was read.
+	head, _ := body.Peek(512)
+
+	format, ok := sniffFormat(head)
+	if !ok {
+		return "", reject("Unsupported sound format. Supported formats: %s.", supportedFormatNames())
+	}
+
+	f, err := os.CreateTemp("", "mog-*"+format.Extension)
+	if err != nil {
+		return "", fmt.Errorf("crate temp: %w", err)
+	}
+	defer f.Close()
+
+	// Attachment size and Content-Length are only hints, the limit is
+	// enforced on what is actually read.
+	n, err := io.Copy(f, io.LimitReader(body, voiceMaxSize+1))
+	if err == nil && n > voiceMaxSize {
+		err = ErrVoiceTooLarge
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("download: %w", err)
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,80 @@
+package welcomevoice
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestInternalIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "127.0.0.1", want: true},
+		{ip: "::1", want: true},
+		{ip: "10.1.2.3", want: true},
+		{ip: "172.16.0.1", want: true},
+		{ip: "192.168.1.1", want: true},
+		{ip: "169.254.169.254", want: true},
+		{ip: "fe80::1", want: true},
+		{ip: "fd00::1", want: true},
+		{ip: "100.64.0.1", want: true},
+		{ip: "0.0.0.0", want: true},
+		{ip: "162.159.128.233", want: false},
+		{ip: "2606:4700::6810:84e5", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := internalIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("internalIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestUploadClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("OggS"))
+	}))
+	defer server.Close()
+
+	_, err := download(context.Background(), newUploadClient(time.Second), server.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("download() error = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
+
+func TestSoundSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantURI     string
+		wantContent string
+		wantErr     bool
+	}{
+		{name: "link", content: "https://example.com/a.mp3 0:01-0:02", wantURI: "https://example.com/a.mp3", wantContent: "  0:01-0:02"},
+		{name: "angle brackets", content: "<http://example.com/a.mp3>", wantURI: "http://example.com/a.mp3", wantContent: " "},
+		{name: "no link", content: "hello", wantErr: true},
+		{name: "two links", content: "https://a.com/1 https://b.com/2", wantErr: true},
+		{name: "other scheme", content: "ftp://example.com/a.mp3", wantErr: true},
+		{name: "no host", content: "https:///a.mp3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uri, content, err := soundSource(&discordgo.Message{Content: tt.content})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("soundSource() error = %v, want error %v", err, tt.wantErr)
+			}
+			if uri != tt.wantURI || content != tt.wantContent {
+				t.Errorf("soundSource() = %q, %q, want %q, %q", uri, content, tt.wantURI, tt.wantContent)
+			}
+		})
+	}
+}
@@ -1,9 +1,37 @@
 package welcomevoice
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
-	ErrExtensionNotFound   = errors.New("extension not found")
 	ErrVoiceTooLarge       = errors.New("voice too large")
 	ErrAttachmentsNotFound = errors.New("attachments not found")
+	ErrUnexpectedStatus    = errors.New("unexpected status")
+	ErrIncompatibleOgg     = errors.New("incompatible ogg")
+	ErrUnknownProvider     = errors.New("unknown provider")
+	ErrNoRandomSound       = errors.New("no random sound")
+	ErrUnknownPolicy       = errors.New("unknown policy")
+	ErrNoModerators        = errors.New("no moderator roles")
+	ErrInvalidSchedule     = errors.New("invalid schedule")
+	ErrUnknownTTS          = errors.New("unknown tts engine")
+	ErrNoTTSModel          = errors.New("no tts model")
+	ErrUnknownMixMode      = errors.New("unknown mix mode")
+	ErrForbiddenAddress    = errors.New("forbidden address")
+	ErrInvalidUserID       = errors.New("invalid user id")
 )
+
+// RejectError is an error caused by the user's input. Its reason is sent
+// back to the user as is.
+type RejectError struct {
+	Reason string
+}
+
+func (e *RejectError) Error() string {
+	return e.Reason
+}
+
+func reject(format string, a ...any) error {
+	return &RejectError{Reason: fmt.Sprintf(format, a...)}
+}
@@ -0,0 +1,342 @@
+package welcomevoice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// FallbackSticky keeps the first random sound forever.
+	FallbackSticky = "sticky"
+	// FallbackEachJoin picks a new random sound on every join.
+	FallbackEachJoin = "each_join"
+	// FallbackDaily picks a new random sound once a day.
+	FallbackDaily = "daily"
+
+	fallbackDir = "fallback"
+)
+
+// fallbackSound is a random sound assigned to a user without an upload.
+type fallbackSound struct {
+	Source string `json:"source"`
+	// Set is the holiday the sound was picked for, empty for the regular
+	// random sounds.
+	Set        string    `json:"set,omitempty"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+func validFallbackPolicy(policy string) bool {
+	switch policy {
+	case FallbackSticky, FallbackEachJoin, FallbackDaily:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *WelcomeVoice) pathFallbackData(userID string) string {
+	return path.Join(w.config.VoiceDir, fallbackDir, userID+voiceExtension)
+}
+
+const (
+	soundUpload  = "upload"
+	soundDefault = "default"
+	soundSpeech  = "speech"
+)
+
+// soundCandidate is a source of the user's greeting.
+type soundCandidate struct {
+	Kind string
+	// Path is the uploaded sound or the configured default.
+	Path string
+	// Source describes the default or holds the name to speak.
+	Source string
+}
+
+// soundCandidates lists the sources of the user's greeting in the order they
+// are tried: the uploaded sound, a role, bot or guild default and a spoken
+// greeting. The fallback sound comes after all of them.
+func (w *WelcomeVoice) soundCandidates(userID, guildID string, member *discordgo.Member) ([]soundCandidate, error) {
+	var candidates []soundCandidate
+
+	p := w.pathSoundData(userID)
+	if _, err := os.Stat(p); err == nil {
+		candidates = append(candidates, soundCandidate{Kind: soundUpload, Path: p})
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("stat sound: %w", err)
+	}
+
+	if sound, source := w.defaultSound(userID, guildID, member); sound != "" {
+		candidates = append(candidates, soundCandidate{Kind: soundDefault, Path: sound, Source: source})
+	}
+
+	if name := displayName(member); w.synthesizer != nil && name != "" {
+		candidates = append(candidates, soundCandidate{Kind: soundSpeech, Source: name})
+	}
+
+	return candidates, nil
+}
+
+// userSound returns the file to play for the user: the first candidate which
+// can be prepared, otherwise a fallback sound chosen by the policy. It is
+// called without the lock, which is only taken to look things up.
+func (w *WelcomeVoice) userSound(ctx context.Context, userID, guildID string, member *discordgo.Member) (string, error) {
+	w.mu.Lock()
+	candidates, err := w.soundCandidates(userID, guildID, member)
+	w.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	for _, c := range candidates {
+		switch c.Kind {
+		case soundUpload:
+			return c.Path, nil
+		case soundDefault:
+			prepared, err := w.prepareDefault(ctx, c.Path)
+			if err == nil {
+				return prepared, nil
+			}
+			w.logger.Printf("user sound: default: prepare %s: %s", c.Path, err.Error())
+		case soundSpeech:
+			speech, err := w.speechSound(ctx, c.Source)
+			if err == nil {
+				return speech, nil
+			}
+			w.logger.Printf("user sound: speech: %s", err.Error())
+		}
+	}
+
+	return w.fallbackSound(ctx, userID)
+}
+
+// fallbackSound returns the user's fallback sound, picking a new one if the
+// policy asks for it.
+func (w *WelcomeVoice) fallbackSound(ctx context.Context, userID string) (string, error) {
+	p := w.pathFallbackData(userID)
+
+	w.mu.Lock()
+	fallback, ok := w.repository.Fallbacks[userID]
+	expired := !ok || w.fallbackExpired(fallback)
+	w.mu.Unlock()
+
+	if !expired {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("stat fallback: %w", err)
+		}
+	}
+
+	staged, fallback, err := w.fetchFallback(ctx)
+	if err != nil {
+		// An expired fallback still beats no greeting at all.
+		if _, statErr := os.Stat(p); statErr == nil {
+			w.logger.Printf("user sound: assign fallback, keeping the old one: %s", err.Error())
+			return p, nil
+		}
+
+		return "", fmt.Errorf("assign fallback: %w", err)
+	}
+	defer os.Remove(staged)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// The user may have uploaded a sound meanwhile.
+	sound := w.pathSoundData(userID)
+	if _, err := os.Stat(sound); err == nil {
+		return sound, nil
+	}
+
+	if err := w.assignFallback(userID, staged, fallback, !ok); err != nil {
+		return "", fmt.Errorf("assign fallback: %w", err)
+	}
+
+	return p, nil
+}
+
+func (w *WelcomeVoice) fallbackExpired(f *fallbackSound) bool {
+	if f.Set != w.fallbackSet().name {
+		return true
+	}
+
+	switch w.config.FallbackPolicy {
+	case FallbackEachJoin:
+		return true
+	case FallbackDaily:
+		y1, m1, d1 := f.AssignedAt.Local().Date()
+		y2, m2, d2 := time.Now().Date()
+
+		return y1 != y2 || m1 != m2 || d1 != d2
+	default:
+		return false
+	}
+}
+
+// fallbackSet returns the random sounds to pick from now: the holiday set if
+// a holiday is going on, the regular one otherwise.
+func (w *WelcomeVoice) fallbackSet() *holiday {
+	if h, ok := w.schedule.activeHoliday(time.Now()); ok {
+		return h
+	}
+
+	return &holiday{random: w.random}
+}
+
+// fetchFallback picks a random sound and converts it to a staging file. It
+// runs without the lock, bounded by the conversion timeout.
+func (w *WelcomeVoice) fetchFallback(ctx context.Context) (string, *fallbackSound, error) {
+	ctx, cancel := w.withConvertTimeout(ctx)
+	defer cancel()
+
+	set := w.fallbackSet()
+
+	sound, err := set.random.RandomSound(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("random sound: %w", err)
+	}
+	defer sound.Close()
+
+	f, err := os.CreateTemp(path.Join(w.config.VoiceDir, stagingDir), "fallback-*"+voiceExtension)
+	if err != nil {
+		return "", nil, fmt.Errorf("create temp: %w", err)
+	}
+	_ = f.Close()
+
+	if err := w.convertSound(ctx, sound.Path, f.Name(), trimRange{}); err != nil {
+		_ = os.Remove(f.Name())
+		return "", nil, fmt.Errorf("convert sound: %w", err)
+	}
+
+	return f.Name(), &fallbackSound{
+		Source:     sound.Source,
+		Set:        set.name,
+		AssignedAt: time.Now(),
+	}, nil
+}
+
+// assignFallback replaces the user's fallback sound with the staged one.
+func (w *WelcomeVoice) assignFallback(userID, staged string, fallback *fallbackSound, first bool) error {
+	if err := os.Rename(staged, w.pathFallbackData(userID)); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	w.repository.Fallbacks[userID] = fallback
+	w.saveRepository()
+
+	// Users with a new sound on every join are only told once.
+	if first || w.config.FallbackPolicy != FallbackEachJoin {
+		w.notifyFallback(userID, fallback)
+	}
+
+	return nil
+}
+
+// removeFallback forgets the fallback sound once the user uploads their own.
+func (w *WelcomeVoice) removeFallback(userID string) {
+	if _, ok := w.repository.Fallbacks[userID]; !ok {
+		return
+	}
+
+	delete(w.repository.Fallbacks, userID)
+	w.saveRepository()
+
+	if err := os.Remove(w.pathFallbackData(userID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		w.logger.Printf("remove fallback: %s", err.Error())
+	}
+}
+
+func (w *WelcomeVoice) notifyFallback(userID string, f *fallbackSound) {
+	if !w.config.FallbackNotify {
+		return
+	}
+
+	var when string
+	switch w.config.FallbackPolicy {
+	case FallbackEachJoin:
+		when = "You will get a new one every time you join."
+	case FallbackDaily:
+		when = "You will get a new one tomorrow."
+	default:
+		when = "It stays yours until you upload your own."
+	}
+
+	if err := w.sendDirect(userID, fmt.Sprintf(
+		"You have no welcome sound, so a random one was picked for you (%s). %s Post a sound in <#%s> to use your own.",
+		f.Source, when, w.config.ChannelID,
+	)); err != nil {
+		w.logger.Printf("notify fallback: %s", err.Error())
+	}
+}
+
+func (w *WelcomeVoice) sendDirect(userID, content string) error {
+	channel, err := w.client.UserChannelCreate(userID)
+	if err != nil {
+		return fmt.Errorf("user channel create: %w", err)
+	}
+
+	if _, err := w.client.ChannelMessageSend(channel.ID, content); err != nil {
+		return fmt.Errorf("channel message send: %w", err)
+	}
+
+	return nil
+}
+
+// migrateFallbacks moves sounds of users without a message in the channel to
+// the fallback directory. Earlier versions stored random sounds as uploads.
+func (w *WelcomeVoice) migrateFallbacks() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entries, err := os.ReadDir(w.config.VoiceDir)
+	if err != nil {
+		return fmt.Errorf("read dir: %w", err)
+	}
+
+	var migrated bool
+	for _, e := range entries {
+		if !e.Type().IsRegular() || path.Ext(e.Name()) != voiceExtension {
+			continue
+		}
+
+		userID := e.Name()[:len(e.Name())-len(voiceExtension)]
+		if strings.Trim(userID, "0123456789") != "" {
+			continue
+		}
+		if _, ok := w.messageByUser[userID]; ok {
+			continue
+		}
+		if _, ok := w.repository.Imports[userID]; ok {
+			continue
+		}
+
+		if err := os.Rename(w.pathSoundData(userID), w.pathFallbackData(userID)); err != nil {
+			return fmt.Errorf("move %s: %w", userID, err)
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			return fmt.Errorf("info %s: %w", userID, err)
+		}
+
+		w.repository.Fallbacks[userID] = &fallbackSound{
+			Source:     "unknown",
+			AssignedAt: info.ModTime(),
+		}
+		migrated = true
+	}
+
+	if migrated {
+		return w.writeRepository()
+	}
+
+	return nil
+}
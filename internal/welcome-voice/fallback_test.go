@@ -0,0 +1,88 @@
+package welcomevoice
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tekig/mog-go/internal/duration"
+)
+
+func TestUserSoundKeepsExpiredFallback(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{FallbackPolicy: FallbackEachJoin})
+
+	if err := os.WriteFile(w.pathFallbackData("1"), []byte("sound"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w.repository.Fallbacks["1"] = &fallbackSound{Source: "old", AssignedAt: time.Now()}
+
+	got, err := w.userSound(context.Background(), "1", "", nil)
+	if err != nil {
+		t.Fatalf("userSound() error = %v", err)
+	}
+	if got != w.pathFallbackData("1") {
+		t.Errorf("userSound() = %s, want the old fallback", got)
+	}
+}
+
+func TestUserSoundWithoutFallback(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{FallbackPolicy: FallbackEachJoin})
+
+	if _, err := w.userSound(context.Background(), "1", "", nil); !errors.Is(err, ErrNoRandomSound) {
+		t.Fatalf("userSound() error = %v, want %v", err, ErrNoRandomSound)
+	}
+}
+
+// lockCheckProvider serves a prepared file and records whether the lock was
+// free and the context had a deadline while the sound was fetched.
+type lockCheckProvider struct {
+	w        *WelcomeVoice
+	path     string
+	unlocked bool
+	deadline bool
+}
+
+func (p *lockCheckProvider) RandomSound(ctx context.Context) (*RandomSound, error) {
+	if p.w.mu.TryLock() {
+		p.unlocked = true
+		p.w.mu.Unlock()
+	}
+	_, p.deadline = ctx.Deadline()
+
+	return &RandomSound{Path: p.path, Source: "test"}, nil
+}
+
+func TestUserSoundFetchesFallbackWithoutLock(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{
+		FallbackPolicy: FallbackSticky,
+		Convert: ConvertConfig{
+			DisableNormalize:   true,
+			DisableTrimSilence: true,
+			Timeout:            duration.Duration{Duration: time.Minute},
+		},
+	})
+	provider := &lockCheckProvider{w: w, path: writeOpus(t, 312, []uint64{960, 1920}, nil)}
+	w.random = provider
+
+	got, err := w.userSound(context.Background(), "1", "", nil)
+	if err != nil {
+		t.Fatalf("userSound() error = %v", err)
+	}
+	if got != w.pathFallbackData("1") {
+		t.Errorf("userSound() = %s, want the fallback", got)
+	}
+	if !provider.unlocked {
+		t.Error("the random sound was fetched under the lock")
+	}
+	if !provider.deadline {
+		t.Error("the random sound was fetched without the conversion timeout")
+	}
+	if f := w.repository.Fallbacks["1"]; f == nil || f.Source != "test" {
+		t.Errorf("fallback = %+v, want one from test", f)
+	}
+	if err := checkSound(got); err != nil {
+		t.Errorf("fallback is not playable: %v", err)
+	}
+}
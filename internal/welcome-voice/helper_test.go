@@ -0,0 +1,155 @@
+package welcomevoice
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type failingProvider struct{}
+
+func (failingProvider) RandomSound(context.Context) (*RandomSound, error) {
+	return nil, ErrNoRandomSound
+}
+
+// offlineTransport fails every request, so Discord calls only log errors.
+type offlineTransport struct{}
+
+func (offlineTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("offline")
+}
+
+// offlineSession is a Discord session with an empty state whose requests
+// fail without reaching the network.
+func offlineSession(t *testing.T) *discordgo.Session {
+	t.Helper()
+
+	s, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Client = &http.Client{Transport: offlineTransport{}}
+	s.State.User = &discordgo.User{ID: "bot"}
+
+	return s
+}
+
+func newTestWelcomeVoice(t *testing.T, config Config) *WelcomeVoice {
+	t.Helper()
+
+	config.VoiceDir = t.TempDir()
+	for _, dir := range []string{fallbackDir, historyDir, blobDir, pendingDir, stagingDir, originalDir} {
+		if err := os.MkdirAll(path.Join(config.VoiceDir, dir), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s, err := newSchedule(config.Schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &WelcomeVoice{
+		config:        config,
+		logger:        log.New(io.Discard, "", 0),
+		random:        failingProvider{},
+		repository:    newRepository(),
+		schedule:      s,
+		channelByUser: make(map[string]string),
+		messageByUser: make(map[string]string),
+		ctx:           context.Background(),
+	}
+}
+
+// oggCRC is the Ogg page checksum: CRC-32 with polynomial 0x04c11db7,
+// no reflection and zero initial value.
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
+
+// oggTestPage builds a page holding the packets, each shorter than 255
+// bytes.
+func oggTestPage(headerType byte, granule uint64, index uint32, packets ...[]byte) []byte {
+	page := make([]byte, 27)
+	copy(page, "OggS")
+	page[5] = headerType
+	binary.LittleEndian.PutUint64(page[6:], granule)
+	binary.LittleEndian.PutUint32(page[14:], 1)
+	binary.LittleEndian.PutUint32(page[18:], index)
+	page[26] = byte(len(packets))
+
+	for _, p := range packets {
+		page = append(page, byte(len(p)))
+	}
+	for _, p := range packets {
+		page = append(page, p...)
+	}
+
+	binary.LittleEndian.PutUint32(page[22:], oggCRC(page))
+
+	return page
+}
+
+func opusHead(channels byte, preSkip uint16) []byte {
+	head := []byte("OpusHead")
+	head = append(head, 1, channels)
+	head = binary.LittleEndian.AppendUint16(head, preSkip)
+	head = binary.LittleEndian.AppendUint32(head, opusSampleRate)
+
+	return append(head, 0, 0, 0)
+}
+
+// frame20ms is a CELT 20ms single frame packet.
+var frame20ms = []byte{31 << 3, 0xAA}
+
+// writeOpus writes a stream with the given granule positions, one packet
+// per page unless packets says otherwise.
+func writeOpus(t *testing.T, preSkip uint16, granules []uint64, packets []int) string {
+	t.Helper()
+
+	data := oggTestPage(0x02, 0, 0, opusHead(2, preSkip))
+	data = append(data, oggTestPage(0, 0, 1, []byte("OpusTags\x00\x00\x00\x00\x00\x00\x00\x00"))...)
+	for i, g := range granules {
+		n := 1
+		if packets != nil {
+			n = packets[i]
+		}
+		var page [][]byte
+		for j := 0; j < n; j++ {
+			page = append(page, frame20ms)
+		}
+		data = append(data, oggTestPage(0, g, uint32(i+2), page...)...)
+	}
+
+	p := filepath.Join(t.TempDir(), "sound.ogg")
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func intPtr(v int) *int {
+	return &v
+}
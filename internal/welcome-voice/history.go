@@ -0,0 +1,209 @@
+package welcomevoice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const historyDir = "history"
+
+// soundVersion is one of the user's sounds kept for rollback. The last
+// version is the active one.
+type soundVersion struct {
+	ID        string    `json:"id"`
+	MessageID string    `json:"message_id,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// historySize is the number of versions kept per user, zero or less turns
+// the history off.
+func (w *WelcomeVoice) historySize() int {
+	if w.config.HistorySize == nil {
+		return 0
+	}
+
+	return *w.config.HistorySize
+}
+
+func (w *WelcomeVoice) pathHistoryData(userID, versionID string) string {
+	return path.Join(w.config.VoiceDir, historyDir, userID, versionID+voiceExtension)
+}
+
+// recordHistory keeps a copy of the user's active sound as a new version and
+// drops the oldest ones over the limit.
+func (w *WelcomeVoice) recordHistory(userID, messageID, source string) error {
+	if w.historySize() <= 0 {
+		return nil
+	}
+
+	v := &soundVersion{
+		ID:        strconv.FormatInt(time.Now().UnixNano(), 36),
+		MessageID: messageID,
+		Source:    source,
+		CreatedAt: time.Now(),
+	}
+
+	if err := os.MkdirAll(path.Dir(w.pathHistoryData(userID, v.ID)), os.ModePerm); err != nil {
+		return fmt.Errorf("mkdir: %w", err)
+	}
+
+	if err := copyFile(w.pathSoundData(userID), w.pathHistoryData(userID, v.ID)); err != nil {
+		return fmt.Errorf("copy: %w", err)
+	}
+
+	versions := append(w.repository.History[userID], v)
+	for len(versions) > w.historySize() {
+		if err := os.Remove(w.pathHistoryData(userID, versions[0].ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			w.logger.Printf("record history: remove old version: %s", err.Error())
+		}
+		versions = versions[1:]
+	}
+
+	w.repository.History[userID] = versions
+	w.saveRepository()
+
+	return nil
+}
+
+// rollbackSound makes the version active again and moves it to the end of
+// the history.
+func (w *WelcomeVoice) rollbackSound(userID string, index int) (*soundVersion, error) {
+	versions := w.repository.History[userID]
+
+	v, err := historyVersion(versions, index)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.checkBanned(w.pathHistoryData(userID, v.ID), nil); err != nil {
+		return nil, err
+	}
+
+	if err := copyFile(w.pathHistoryData(userID, v.ID), w.pathSoundData(userID)); err != nil {
+		return nil, fmt.Errorf("copy: %w", err)
+	}
+
+	next := make([]*soundVersion, 0, len(versions))
+	for _, other := range versions {
+		if other != v {
+			next = append(next, other)
+		}
+	}
+	w.repository.History[userID] = append(next, v)
+
+	// The original belongs to the replaced sound, re-encoding it would undo
+	// the rollback.
+	w.setOriginal(userID, nil)
+	w.removeFallback(userID)
+
+	if err := w.dedupSound(userID); err != nil {
+		w.logger.Printf("rollback: dedup sound: %s", err.Error())
+	}
+
+	return v, nil
+}
+
+// historyVersion returns the version by its number in the list shown to the
+// user, 1 is the active one.
+func historyVersion(versions []*soundVersion, index int) (*soundVersion, error) {
+	if index < 1 || index > len(versions) {
+		return nil, reject("There is no version #%d, see the `history` command.", index)
+	}
+
+	return versions[len(versions)-index], nil
+}
+
+func (w *WelcomeVoice) commandHistory(c *commandContext, args []string) (*discordgo.MessageSend, error) {
+	if len(args) == 0 {
+		return w.historyList(c.Author.ID)
+	}
+	if len(args) < 2 {
+		return nil, reject("Which version? Use `%s history %s <number>`.", w.config.CommandPrefix, args[0])
+	}
+
+	index, err := strconv.Atoi(args[1])
+	if err != nil {
+		return nil, reject("`%s` is not a version number.", args[1])
+	}
+
+	switch args[0] {
+	case "preview":
+		return w.historyPreview(c.Author.ID, index)
+	case "rollback":
+		return w.historyRollback(c.Author.ID, index)
+	default:
+		return nil, reject("Unknown action `%s`, use `preview` or `rollback`.", args[0])
+	}
+}
+
+func (w *WelcomeVoice) historyList(userID string) (*discordgo.MessageSend, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	versions := w.repository.History[userID]
+	if len(versions) == 0 {
+		return textReply("You have no saved sounds."), nil
+	}
+
+	var b strings.Builder
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+
+		fmt.Fprintf(&b, "%d. <t:%d:f>", len(versions)-i, v.CreatedAt.Unix())
+		if v.Source != "" {
+			fmt.Fprintf(&b, " — %s", v.Source)
+		}
+		if i == len(versions)-1 {
+			b.WriteString(" (active)")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "Use `%s history preview <number>` or `%s history rollback <number>`.", w.config.CommandPrefix, w.config.CommandPrefix)
+
+	return textReply("%s", b.String()), nil
+}
+
+func (w *WelcomeVoice) historyPreview(userID string, index int) (*discordgo.MessageSend, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	v, err := historyVersion(w.repository.History[userID], index)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(w.pathHistoryData(userID, v.ID))
+	if err != nil {
+		return nil, fmt.Errorf("read version: %w", err)
+	}
+
+	return &discordgo.MessageSend{
+		Content: fmt.Sprintf("Version #%d from <t:%d:f>.", index, v.CreatedAt.Unix()),
+		Files: []*discordgo.File{{
+			Name:        "sound-" + strconv.Itoa(index) + voiceExtension,
+			ContentType: "audio/ogg",
+			Reader:      bytes.NewReader(data),
+		}},
+	}, nil
+}
+
+func (w *WelcomeVoice) historyRollback(userID string, index int) (*discordgo.MessageSend, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	v, err := w.rollbackSound(userID, index)
+	if err != nil {
+		return nil, err
+	}
+
+	return textReply("Your welcome sound is the one from <t:%d:f> again.", v.CreatedAt.Unix()), nil
+}
@@ -0,0 +1,79 @@
+package welcomevoice
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestRecordHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    *int
+		uploads int
+		want    int
+	}{
+		{name: "not set", uploads: 2, want: 0},
+		{name: "disabled", size: intPtr(0), uploads: 2, want: 0},
+		{name: "negative", size: intPtr(-1), uploads: 2, want: 0},
+		{name: "within limit", size: intPtr(3), uploads: 2, want: 2},
+		{name: "over limit", size: intPtr(2), uploads: 4, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWelcomeVoice(t, Config{HistorySize: tt.size})
+			if err := os.WriteFile(w.pathSoundData("1"), []byte("sound"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			for i := 0; i < tt.uploads; i++ {
+				if err := w.recordHistory("1", "m", "upload"); err != nil {
+					t.Fatalf("recordHistory() error = %v", err)
+				}
+			}
+
+			versions := w.repository.History["1"]
+			if len(versions) != tt.want {
+				t.Fatalf("got %d versions, want %d", len(versions), tt.want)
+			}
+			for _, v := range versions {
+				if _, err := os.Stat(w.pathHistoryData("1", v.ID)); err != nil {
+					t.Errorf("version %s: %v", v.ID, err)
+				}
+			}
+		})
+	}
+}
+
+func TestRollbackSoundBanned(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{HistorySize: intPtr(2)})
+
+	for _, data := range []string{"banned", "active"} {
+		if err := os.WriteFile(w.pathSoundData("1"), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.recordHistory("1", "m", "upload"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hash, err := hashFile(w.pathHistoryData("1", w.repository.History["1"][0].ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.repository.Banned = []*bannedSound{{Hashes: []string{hash}}}
+
+	var rejectErr *RejectError
+	if _, err := w.rollbackSound("1", 2); !errors.As(err, &rejectErr) {
+		t.Fatalf("rollbackSound() error = %v, want a rejection", err)
+	}
+
+	data, err := os.ReadFile(w.pathSoundData("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "active" {
+		t.Errorf("sound = %q, want the active one", data)
+	}
+}
@@ -0,0 +1,165 @@
+package welcomevoice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// soundInfo describes the sound played for the user.
+type soundInfo struct {
+	Path   string
+	Source string
+	SetAt  time.Time
+	Plays  int
+}
+
+// currentSoundInfo returns the sound the user hears on join without
+// assigning a new fallback. It is called without the lock, a default sound is
+// prepared outside of it.
+func (w *WelcomeVoice) currentSoundInfo(ctx context.Context, userID, guildID string, member *discordgo.Member) (*soundInfo, error) {
+	w.mu.Lock()
+	candidates, err := w.soundCandidates(userID, guildID, member)
+	w.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range candidates {
+		switch c.Kind {
+		case soundUpload:
+			w.mu.Lock()
+			defer w.mu.Unlock()
+
+			info := w.newSoundInfo(userID, c.Path, "upload")
+			if versions := w.repository.History[userID]; len(versions) > 0 {
+				v := versions[len(versions)-1]
+				info.SetAt = v.CreatedAt
+				if v.Source != "" {
+					info.Source += " " + v.Source
+				}
+			} else if imported, ok := w.repository.Imports[userID]; ok {
+				info.Source = "import"
+				if imported.Source != "" {
+					info.Source += " " + imported.Source
+				}
+				info.SetAt = imported.CreatedAt
+			} else if messageID, ok := w.messageByUser[userID]; ok {
+				info.SetAt, _ = discordgo.SnowflakeTimestamp(messageID)
+			}
+
+			return info, nil
+		case soundDefault:
+			prepared, err := w.prepareDefault(ctx, c.Path)
+			if err != nil {
+				w.logger.Printf("info: default: prepare %s: %s", c.Path, err.Error())
+				continue
+			}
+
+			w.mu.Lock()
+			defer w.mu.Unlock()
+
+			return w.newSoundInfo(userID, prepared, c.Source), nil
+		case soundSpeech:
+			return nil, reject("You have no sound yet, a spoken greeting is played when you join a voice channel.")
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	fallback, ok := w.repository.Fallbacks[userID]
+	if !ok {
+		return nil, reject("You have no sound yet, a random one will be picked when you join a voice channel.")
+	}
+
+	info := w.newSoundInfo(userID, w.pathFallbackData(userID), "random "+fallback.Source)
+	info.SetAt = fallback.AssignedAt
+
+	return info, nil
+}
+
+// newSoundInfo must be called with w.mu held.
+func (w *WelcomeVoice) newSoundInfo(userID, path, source string) *soundInfo {
+	info := &soundInfo{Path: path, Source: source}
+	if stats, ok := w.repository.UserStats[userID]; ok {
+		info.Plays = stats.Plays
+	}
+
+	return info
+}
+
+func (w *WelcomeVoice) commandInfo(c *commandContext, _ []string) (*discordgo.MessageSend, error) {
+	// Members of messages come without the user.
+	member := c.Member
+	if member != nil && member.User == nil {
+		m := *member
+		m.User = c.Author
+		member = &m
+	}
+
+	info, err := w.currentSoundInfo(w.ctx, c.Author.ID, c.GuildID, member)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sounds are only ever replaced by a rename, the file is read whole.
+	data, err := os.ReadFile(info.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read sound: %w", err)
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Source: %s\n", info.Source)
+	if !info.SetAt.IsZero() {
+		fmt.Fprintf(&b, "Set: <t:%d:f>\n", info.SetAt.Unix())
+	}
+
+	// The file is inspected from the copy read above, the stored one may be
+	// replaced meanwhile.
+	temp, err := writeTemp(data)
+	if err != nil {
+		return nil, fmt.Errorf("write temp: %w", err)
+	}
+	defer os.Remove(temp)
+
+	if ogg, err := inspectOgg(temp); err == nil {
+		fmt.Fprintf(&b, "Duration: %s\n", ogg.Duration.Round(10*time.Millisecond))
+	}
+	if lufs, err := measureLoudness(w.ctx, temp); err == nil {
+		fmt.Fprintf(&b, "Loudness: %.1f LUFS\n", lufs)
+	} else {
+		w.logger.Printf("info: measure loudness: %s", err.Error())
+	}
+	fmt.Fprintf(&b, "Played: %d times", info.Plays)
+
+	return &discordgo.MessageSend{
+		Content: b.String(),
+		Files: []*discordgo.File{{
+			Name:        "sound" + voiceExtension,
+			ContentType: "audio/ogg",
+			Reader:      bytes.NewReader(data),
+		}},
+	}, nil
+}
+
+func writeTemp(data []byte) (string, error) {
+	f, err := os.CreateTemp("", "mog-*"+voiceExtension)
+	if err != nil {
+		return "", fmt.Errorf("create temp: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("write: %w", err)
+	}
+
+	return f.Name(), nil
+}
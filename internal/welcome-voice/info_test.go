@@ -0,0 +1,61 @@
+package welcomevoice
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCurrentSoundInfoOrder(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{
+		// Passthrough copies the defaults, no ffmpeg is needed.
+		Convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true},
+	})
+	if err := os.MkdirAll(path.Join(w.config.VoiceDir, defaultsDir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	roleSound := writeOpus(t, 312, []uint64{960}, nil)
+	guildSound := writeOpus(t, 312, []uint64{960}, nil)
+	w.config.Defaults = DefaultsConfig{
+		Roles:  []RoleSoundConfig{{RoleID: "r1", Sound: roleSound}},
+		Guilds: map[string]string{"g1": guildSound},
+	}
+	w.repository.Fallbacks["1"] = &fallbackSound{Source: "random.ogg"}
+
+	member := &discordgo.Member{User: &discordgo.User{ID: "1"}, Roles: []string{"r1"}}
+
+	for _, tt := range []struct {
+		name    string
+		guildID string
+		member  *discordgo.Member
+		want    string
+	}{
+		{"fallback", "g2", &discordgo.Member{User: &discordgo.User{ID: "1"}}, "random random.ogg"},
+		{"guild", "g1", &discordgo.Member{User: &discordgo.User{ID: "1"}}, "guild default"},
+		{"role", "g1", member, "role default"},
+	} {
+		info, err := w.currentSoundInfo(context.Background(), "1", tt.guildID, tt.member)
+		if err != nil {
+			t.Fatalf("%s: currentSoundInfo() error = %v", tt.name, err)
+		}
+		if info.Source != tt.want {
+			t.Errorf("%s: source = %q, want %q", tt.name, info.Source, tt.want)
+		}
+	}
+
+	if err := os.WriteFile(w.pathSoundData("1"), []byte("sound"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := w.currentSoundInfo(context.Background(), "1", "g1", member)
+	if err != nil {
+		t.Fatalf("currentSoundInfo() error = %v", err)
+	}
+	if info.Source != "upload" || info.Path != w.pathSoundData("1") {
+		t.Errorf("currentSoundInfo() = %+v, want the upload", info)
+	}
+}
@@ -0,0 +1,258 @@
+package welcomevoice
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/tekig/mog-go/internal/duration"
+)
+
+const (
+	// MixOverlay plays the greetings of users who joined together at once.
+	MixOverlay = "mix"
+	// MixConcat plays shortened greetings one after another.
+	MixConcat = "concat"
+)
+
+type MixConfig struct {
+	// Mode is "mix" or "concat", greetings are played one by one if empty.
+	Mode string `json:"mode,omitempty"`
+	// Window is how long joins to one channel are collected.
+	Window duration.Duration `json:"window,omitempty"`
+	// Gain scales mixed greetings, 1/sqrt(count) if zero.
+	Gain float64 `json:"gain,omitempty"`
+	// ClipLength is the length every greeting is cut to in concat mode.
+	ClipLength duration.Duration `json:"clip_length,omitempty"`
+}
+
+func validMixMode(mode string) bool {
+	switch mode {
+	case "", MixOverlay, MixConcat:
+		return true
+	default:
+		return false
+	}
+}
+
+// greetingBatch collects the greetings of a channel during the window.
+type greetingBatch struct {
+	guildID   string
+	channelID string
+	items     []*batchGreeting
+	timer     *time.Timer
+}
+
+type batchGreeting struct {
+	userID string
+	sound  string
+	// played is the file to play, a scaled copy of sound which is removed
+	// after the batch if temporary is set.
+	played    string
+	temporary bool
+}
+
+func (g *batchGreeting) close() {
+	if g.temporary {
+		_ = os.Remove(g.played)
+	}
+}
+
+// batchGreeting adds the greeting to the channel's batch, the batch is
+// played when the window ends. A user who rejoins within the window is
+// greeted once, with the latest sound. It must be called with w.mu held.
+func (w *WelcomeVoice) batchGreeting(guildID, channelID string, g *batchGreeting) {
+	b, ok := w.batches[channelID]
+	if !ok {
+		b = &greetingBatch{
+			guildID:   guildID,
+			channelID: channelID,
+		}
+		b.timer = time.AfterFunc(w.config.Mix.Window.Duration, func() {
+			w.playBatch(b)
+		})
+		w.batches[channelID] = b
+	}
+
+	for i, other := range b.items {
+		if other.userID == g.userID {
+			other.close()
+			b.items[i] = g
+			return
+		}
+	}
+
+	b.items = append(b.items, g)
+}
+
+// playBatch combines and plays the greetings without holding the lock, it is
+// only held to take the batch and to record the playback.
+func (w *WelcomeVoice) playBatch(b *greetingBatch) {
+	w.mu.Lock()
+	if w.batches[b.channelID] != b {
+		w.mu.Unlock()
+		return
+	}
+	delete(w.batches, b.channelID)
+	w.mu.Unlock()
+
+	defer func() {
+		for _, g := range b.items {
+			g.close()
+		}
+	}()
+
+	sound := b.items[0].played
+	if len(b.items) > 1 {
+		ctx, cancel := w.withConvertTimeout(w.ctx)
+		combined, err := w.combineGreetings(ctx, b.items)
+		cancel()
+		if err != nil {
+			w.logger.Printf("play batch: combine: %s", err.Error())
+			return
+		}
+		defer os.Remove(combined)
+
+		sound = combined
+	}
+
+	p, err := w.play(sound, b.guildID, b.channelID)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for i, itemPlayback := range w.itemPlaybacks(b.items, p) {
+		g := b.items[i]
+		w.recordPlayback(playKindGreeting, g.userID, g.sound, b.guildID, b.channelID, itemPlayback, err)
+	}
+	if err != nil {
+		w.logger.Printf("play batch: play: %s", err.Error())
+		return
+	}
+
+	for _, g := range b.items {
+		w.greeted(g.userID, b.channelID)
+	}
+}
+
+// itemPlaybacks splits the playback of the batch into what every greeting
+// got of it. Mixed greetings start at once, concatenated ones follow each
+// other cut to the clip length.
+func (w *WelcomeVoice) itemPlaybacks(items []*batchGreeting, p *playback) []*playback {
+	result := make([]*playback, len(items))
+	if p == nil || len(items) == 1 {
+		for i := range result {
+			result[i] = p
+		}
+		return result
+	}
+
+	var start time.Duration
+	for i, g := range items {
+		length := p.Played - start
+		if info, err := inspectOgg(g.played); err == nil {
+			length = info.Duration
+		}
+
+		span := length
+		if clip := w.config.Mix.ClipLength.Duration; w.config.Mix.Mode == MixConcat && clip > 0 && span > clip {
+			span = clip
+		}
+
+		played := p.Played - start
+		if played < 0 {
+			played = 0
+		}
+		if played > span {
+			played = span
+		}
+
+		result[i] = &playback{Played: played, CutShort: played < length}
+		if w.config.Mix.Mode == MixConcat {
+			start += span
+		}
+	}
+
+	return result
+}
+
+// stopBatches drops the greetings which were not played yet.
+func (w *WelcomeVoice) stopBatches() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for channelID, b := range w.batches {
+		b.timer.Stop()
+		for _, g := range b.items {
+			g.close()
+		}
+		delete(w.batches, channelID)
+	}
+}
+
+// combineGreetings writes the greetings mixed or concatenated, according to
+// the mode, to a temporary file. The caller removes it.
+func (w *WelcomeVoice) combineGreetings(ctx context.Context, items []*batchGreeting) (string, error) {
+	f, err := os.CreateTemp("", "mog-*"+voiceExtension)
+	if err != nil {
+		return "", fmt.Errorf("create temp: %w", err)
+	}
+	_ = f.Close()
+
+	args := []string{"-y"}
+	for _, g := range items {
+		args = append(args, "-i", g.played)
+	}
+	args = append(args, "-filter_complex", w.combineFilter(len(items)))
+
+	if d := w.config.VoiceDuration.Duration; d > 0 {
+		args = append(args, "-t", formatSeconds(d.Seconds()))
+	}
+	args = append(args, "-ar", "48000", "-c:a", "libopus", "-page_duration", "20000", f.Name())
+
+	output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("ffmpeg: %s, %w", string(output), err)
+	}
+
+	return f.Name(), nil
+}
+
+func (w *WelcomeVoice) combineFilter(n int) string {
+	var b strings.Builder
+
+	// Inputs may differ in channels, both filters need them alike.
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "[%d:a]aformat=sample_rates=48000:channel_layouts=stereo", i)
+		if w.config.Mix.Mode == MixConcat {
+			clip := w.config.Mix.ClipLength.Duration.Seconds()
+			fade := math.Min(0.3, clip/4)
+			fmt.Fprintf(&b, ",atrim=end=%s,asetpts=PTS-STARTPTS,afade=t=out:st=%s:d=%s",
+				formatSeconds(clip), formatSeconds(clip-fade), formatSeconds(fade))
+		}
+		fmt.Fprintf(&b, "[a%d];", i)
+	}
+
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "[a%d]", i)
+	}
+
+	if w.config.Mix.Mode == MixConcat {
+		fmt.Fprintf(&b, "concat=n=%d:v=0:a=1", n)
+		return b.String()
+	}
+
+	gain := w.config.Mix.Gain
+	if gain == 0 {
+		gain = 1 / math.Sqrt(float64(n))
+	}
+	// The limiter keeps loud overlapping parts from clipping.
+	fmt.Fprintf(&b, "amix=inputs=%d:duration=longest:normalize=0,volume=%s,alimiter=limit=0.95", n, formatFloat(gain))
+
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package welcomevoice
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tekig/mog-go/internal/duration"
+)
+
+func TestItemPlaybacks(t *testing.T) {
+	// Two pages of 20 ms each, 40 ms in total.
+	short := writeOpus(t, 0, []uint64{960, 1920}, nil)
+	// 100 ms.
+	long := writeOpus(t, 0, []uint64{960, 1920, 2880, 3840, 4800}, nil)
+	items := []*batchGreeting{{userID: "1", played: short}, {userID: "2", played: long}}
+
+	tests := []struct {
+		name   string
+		config MixConfig
+		played time.Duration
+		want   []playback
+	}{
+		{
+			name:   "mix",
+			config: MixConfig{Mode: MixOverlay},
+			played: 100 * time.Millisecond,
+			want:   []playback{{Played: 40 * time.Millisecond}, {Played: 100 * time.Millisecond}},
+		},
+		{
+			name:   "mix cut short",
+			config: MixConfig{Mode: MixOverlay},
+			played: 60 * time.Millisecond,
+			want:   []playback{{Played: 40 * time.Millisecond}, {Played: 60 * time.Millisecond, CutShort: true}},
+		},
+		{
+			name:   "concat",
+			config: MixConfig{Mode: MixConcat, ClipLength: duration.Duration{Duration: 60 * time.Millisecond}},
+			played: 100 * time.Millisecond,
+			want:   []playback{{Played: 40 * time.Millisecond}, {Played: 60 * time.Millisecond, CutShort: true}},
+		},
+		{
+			name:   "concat cut short",
+			config: MixConfig{Mode: MixConcat, ClipLength: duration.Duration{Duration: time.Second}},
+			played: 20 * time.Millisecond,
+			want:   []playback{{Played: 20 * time.Millisecond, CutShort: true}, {CutShort: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWelcomeVoice(t, Config{Mix: tt.config})
+
+			got := w.itemPlaybacks(items, &playback{Played: tt.played})
+			for i, p := range got {
+				if *p != tt.want[i] {
+					t.Errorf("item %d = %+v, want %+v", i, *p, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCombineFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		config MixConfig
+		n      int
+		// want are parameters of the whole graph, perInput are repeated
+		// for every greeting.
+		want     []string
+		perInput []string
+	}{
+		{
+			name:   "mix",
+			config: MixConfig{Mode: MixOverlay},
+			n:      2,
+			want:   []string{"amix=inputs=2:", "volume=0.7071067811865475"},
+		},
+		{
+			name:   "mix gain",
+			config: MixConfig{Mode: MixOverlay, Gain: 0.5},
+			n:      3,
+			want:   []string{"amix=inputs=3:", "volume=0.5"},
+		},
+		{
+			name:     "concat",
+			config:   MixConfig{Mode: MixConcat, ClipLength: duration.Duration{Duration: 2 * time.Second}},
+			n:        2,
+			want:     []string{"concat=n=2:"},
+			perInput: []string{"atrim=end=2.000"},
+		},
+		{
+			name:     "concat short clip",
+			config:   MixConfig{Mode: MixConcat, ClipLength: duration.Duration{Duration: 800 * time.Millisecond}},
+			n:        1,
+			want:     []string{"concat=n=1:"},
+			perInput: []string{"atrim=end=0.800"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWelcomeVoice(t, Config{Mix: tt.config})
+			got := w.combineFilter(tt.n)
+
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("combineFilter() = %s, want %s", got, want)
+				}
+			}
+			for _, want := range tt.perInput {
+				if c := strings.Count(got, want); c != tt.n {
+					t.Errorf("combineFilter() = %s, want %s %d times, got %d", got, want, tt.n, c)
+				}
+			}
+			for i := 0; i < tt.n; i++ {
+				if input := fmt.Sprintf("[%d:a]", i); !strings.Contains(got, input) {
+					t.Errorf("combineFilter() = %s, want input %s", got, input)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchGreetingReplacesRejoin(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{Mix: MixConfig{Mode: MixOverlay, Window: duration.Duration{Duration: time.Hour}}})
+	w.batches = make(map[string]*greetingBatch)
+
+	w.batchGreeting("g", "c", &batchGreeting{userID: "1", played: "first"})
+	w.batchGreeting("g", "c", &batchGreeting{userID: "2", played: "other"})
+	w.batchGreeting("g", "c", &batchGreeting{userID: "1", played: "second"})
+
+	b := w.batches["c"]
+	b.timer.Stop()
+
+	if len(b.items) != 2 {
+		t.Fatalf("got %d greetings, want 2", len(b.items))
+	}
+	if b.items[0].played != "second" {
+		t.Errorf("greeting of 1 = %s, want the latest", b.items[0].played)
+	}
+}
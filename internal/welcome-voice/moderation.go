@@ -0,0 +1,328 @@
+package welcomevoice
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+const pendingDir = "pending"
+
+type ModerationConfig struct {
+	Enabled      bool     `json:"enabled,omitempty"`
+	RoleIDs      []string `json:"role_ids,omitempty"`
+	ApproveEmoji string   `json:"approve_emoji,omitempty"`
+	RejectEmoji  string   `json:"reject_emoji,omitempty"`
+}
+
+// pendingSound is an upload waiting for a moderator. The previously approved
+// sound stays active until it is approved.
+type pendingSound struct {
+	MessageID   string         `json:"message_id"`
+	Source      string         `json:"source,omitempty"`
+	Original    *originalSound `json:"original,omitempty"`
+	SubmittedAt time.Time      `json:"submitted_at"`
+}
+
+func (w *WelcomeVoice) pathPendingData(userID string) string {
+	return path.Join(w.config.VoiceDir, pendingDir, userID+voiceExtension)
+}
+
+func (w *WelcomeVoice) isModerator(member *discordgo.Member) bool {
+	if member == nil {
+		return false
+	}
+
+	return slices.IndexFunc(member.Roles, func(role string) bool {
+		return slices.Contains(w.config.Moderation.RoleIDs, role)
+	}) != -1
+}
+
+// submitSound puts the staged sound in the moderation queue,
+// replacing the user's previous pending sound.
+func (w *WelcomeVoice) submitSound(m *discordgo.Message, staged string, original *originalSound) error {
+	if err := os.Rename(staged, w.pathPendingData(m.Author.ID)); err != nil {
+		return fmt.Errorf("rename: %w", err)
+	}
+
+	if old, ok := w.repository.Pending[m.Author.ID]; ok && old.MessageID != m.ID {
+		if err := w.client.ChannelMessageDelete(m.ChannelID, old.MessageID); err != nil {
+			w.logger.Printf("remove old pending message: %s", err.Error())
+		}
+		if old.Original != nil {
+			w.removeOriginal(old.Original)
+		}
+	}
+
+	w.repository.Pending[m.Author.ID] = &pendingSound{
+		MessageID:   m.ID,
+		Source:      sourceName(m),
+		Original:    original,
+		SubmittedAt: time.Now(),
+	}
+	w.enforceOriginalQuota()
+	w.saveRepository()
+
+	for _, emoji := range []string{w.config.Moderation.ApproveEmoji, w.config.Moderation.RejectEmoji} {
+		if err := w.client.MessageReactionAdd(m.ChannelID, m.ID, emoji); err != nil {
+			return fmt.Errorf("reaction add: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// approveSound makes the user's pending sound active.
+func (w *WelcomeVoice) approveSound(userID, guildID string) error {
+	p, ok := w.repository.Pending[userID]
+	if !ok {
+		return reject("<@%s> has no pending sound.", userID)
+	}
+
+	// The sound may have been banned while it waited.
+	if err := w.checkBanned(w.pathPendingData(userID), p.Original); err != nil {
+		var rejectErr *RejectError
+		if !errors.As(err, &rejectErr) {
+			return err
+		}
+
+		if err := w.rejectSound(userID, "the sound is banned on the server"); err != nil {
+			return err
+		}
+
+		return reject("The sound of <@%s> is banned, it was rejected.", userID)
+	}
+
+	if err := os.Rename(w.pathPendingData(userID), w.pathSoundData(userID)); err != nil {
+		return fmt.Errorf("move pending: %w", err)
+	}
+
+	delete(w.repository.Pending, userID)
+	w.setOriginal(userID, p.Original)
+
+	if err := w.client.MessageReactionsRemoveAll(w.config.ChannelID, p.MessageID); err != nil {
+		w.logger.Printf("approve: reactions remove: %s", err.Error())
+	}
+	if err := w.client.MessageReactionAdd(w.config.ChannelID, p.MessageID, w.config.Emoji); err != nil {
+		w.logger.Printf("approve: reaction add: %s", err.Error())
+	}
+
+	w.commitSound(userID, p.MessageID, p.Source, guildID)
+	if m, err := w.client.ChannelMessage(w.config.ChannelID, p.MessageID); err == nil {
+		w.postPreview(userID, m)
+	} else {
+		w.logger.Printf("approve: channel message: %s", err.Error())
+	}
+
+	if err := w.sendDirect(userID, "Your welcome sound was approved."); err != nil {
+		w.logger.Printf("approve: notify: %s", err.Error())
+	}
+
+	return nil
+}
+
+// rejectSound drops the user's pending sound, the active one stays.
+func (w *WelcomeVoice) rejectSound(userID, reason string) error {
+	p, ok := w.repository.Pending[userID]
+	if !ok {
+		return reject("<@%s> has no pending sound.", userID)
+	}
+
+	delete(w.repository.Pending, userID)
+	w.saveRepository()
+
+	if err := os.Remove(w.pathPendingData(userID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		w.logger.Printf("reject: remove pending: %s", err.Error())
+	}
+	if p.Original != nil {
+		w.removeOriginal(p.Original)
+	}
+	if err := w.client.ChannelMessageDelete(w.config.ChannelID, p.MessageID); err != nil {
+		w.logger.Printf("reject: remove message: %s", err.Error())
+	}
+
+	notice := "Your welcome sound was rejected."
+	if reason != "" {
+		notice += " Reason: " + reason
+	}
+	if err := w.sendDirect(userID, notice); err != nil {
+		w.logger.Printf("reject: notify: %s", err.Error())
+	}
+
+	return nil
+}
+
+func (w *WelcomeVoice) onReactionAdd(_ *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if !w.config.Moderation.Enabled || r.ChannelID != w.config.ChannelID || r.UserID == w.client.State.User.ID {
+		return
+	}
+
+	approve := r.Emoji.Name == w.config.Moderation.ApproveEmoji
+	if !approve && r.Emoji.Name != w.config.Moderation.RejectEmoji {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var userID string
+	for id, p := range w.repository.Pending {
+		if p.MessageID == r.MessageID {
+			userID = id
+			break
+		}
+	}
+	if userID == "" {
+		return
+	}
+
+	if !w.isModerator(r.Member) {
+		if err := w.client.MessageReactionRemove(r.ChannelID, r.MessageID, r.Emoji.APIName(), r.UserID); err != nil {
+			w.logger.Printf("on reaction add: remove: %s", err.Error())
+		}
+		return
+	}
+
+	var err error
+	if approve {
+		err = w.approveSound(userID, r.GuildID)
+	} else {
+		err = w.rejectSound(userID, "")
+	}
+	if err != nil {
+		w.logger.Printf("on reaction add: %s", err.Error())
+	}
+}
+
+// loadPending restores the queue from the channel on start. It reports
+// whether the message was handled as an unapproved one.
+func (w *WelcomeVoice) loadPending(m *discordgo.Message, seen map[string]bool) (bool, error) {
+	if w.markedAsDone(m) {
+		return false, nil
+	}
+
+	// Only the newest unapproved message newer than the active one is kept.
+	if _, ok := w.messageByUser[m.Author.ID]; ok || seen[m.Author.ID] {
+		if err := w.client.ChannelMessageDelete(m.ChannelID, m.ID); err != nil {
+			return true, fmt.Errorf("remove old message: %w", err)
+		}
+
+		return true, nil
+	}
+	seen[m.Author.ID] = true
+
+	if p, ok := w.repository.Pending[m.Author.ID]; ok && p.MessageID == m.ID {
+		if _, err := os.Stat(w.pathPendingData(m.Author.ID)); err == nil {
+			return true, nil
+		}
+	}
+
+	if err := w.uploadSound(w.ctx, m, w.submitSound); err != nil {
+		w.logger.Printf("load pending: submit: %s", err.Error())
+		w.replyReject(m, err)
+		delete(seen, m.Author.ID)
+
+		if err := w.client.ChannelMessageDelete(m.ChannelID, m.ID); err != nil {
+			return true, fmt.Errorf("remove message: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// dropStalePending forgets pending sounds whose message is gone.
+func (w *WelcomeVoice) dropStalePending(seen map[string]bool) {
+	var changed bool
+	for userID := range w.repository.Pending {
+		if seen[userID] {
+			continue
+		}
+
+		if p := w.repository.Pending[userID]; p.Original != nil {
+			w.removeOriginal(p.Original)
+		}
+		delete(w.repository.Pending, userID)
+		changed = true
+
+		if err := os.Remove(w.pathPendingData(userID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			w.logger.Printf("drop stale pending: %s", err.Error())
+		}
+	}
+
+	if changed {
+		w.saveRepository()
+	}
+}
+
+func (w *WelcomeVoice) commandPending(c *commandContext, _ []string) (*discordgo.MessageSend, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.repository.Pending) == 0 {
+		return textReply("No sounds are waiting for approval."), nil
+	}
+
+	userIDs := make([]string, 0, len(w.repository.Pending))
+	for userID := range w.repository.Pending {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool {
+		return w.repository.Pending[userIDs[i]].SubmittedAt.Before(w.repository.Pending[userIDs[j]].SubmittedAt)
+	})
+
+	var b strings.Builder
+	for _, userID := range userIDs {
+		p := w.repository.Pending[userID]
+		fmt.Fprintf(&b, "<@%s> — https://discord.com/channels/%s/%s/%s, <t:%d:R>\n",
+			userID, c.GuildID, w.config.ChannelID, p.MessageID, p.SubmittedAt.Unix())
+	}
+
+	return textReply("%s", b.String()), nil
+}
+
+func (w *WelcomeVoice) commandApprove(c *commandContext, args []string) (*discordgo.MessageSend, error) {
+	if len(args) < 1 {
+		return nil, reject("Whose sound should be approved?")
+	}
+
+	userID, err := parseUser(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.approveSound(userID, c.GuildID); err != nil {
+		return nil, err
+	}
+
+	return textReply("Approved the sound of <@%s>.", userID), nil
+}
+
+func (w *WelcomeVoice) commandReject(_ *commandContext, args []string) (*discordgo.MessageSend, error) {
+	if len(args) < 1 {
+		return nil, reject("Whose sound should be rejected?")
+	}
+
+	userID, err := parseUser(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.rejectSound(userID, strings.Join(args[1:], " ")); err != nil {
+		return nil, err
+	}
+
+	return textReply("Rejected the sound of <@%s>.", userID), nil
+}
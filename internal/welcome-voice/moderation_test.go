@@ -0,0 +1,157 @@
+package welcomevoice
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func newModerationTest(t *testing.T) *WelcomeVoice {
+	t.Helper()
+
+	w := newTestWelcomeVoice(t, Config{
+		ChannelID:  "c",
+		Moderation: ModerationConfig{Enabled: true, ApproveEmoji: "✅", RejectEmoji: "❌"},
+	})
+	w.client = offlineSession(t)
+
+	if err := os.WriteFile(w.pathSoundData("1"), []byte("active"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return w
+}
+
+// submitTest puts a sound with its original in the queue of user 1.
+func submitTest(t *testing.T, w *WelcomeVoice, messageID string) *originalSound {
+	t.Helper()
+
+	upload := writeOpus(t, 312, []uint64{960, 1920}, nil)
+	o, err := w.storeOriginal(messageID, upload, trimRange{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	staged := w.pathSoundData("staged")
+	if err := os.WriteFile(staged, []byte("pending "+messageID), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &discordgo.Message{ID: messageID, ChannelID: "c", Author: &discordgo.User{ID: "1"}}
+	// The queue is updated before the reactions, which can not be added
+	// offline.
+	if err := w.submitSound(m, staged, o); err == nil {
+		t.Fatal("submitSound() reached Discord")
+	}
+
+	return o
+}
+
+func readTestFile(t *testing.T, p string) string {
+	t.Helper()
+
+	b, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestSubmitKeepsActiveSound(t *testing.T) {
+	w := newModerationTest(t)
+	first := submitTest(t, w, "m1")
+	submitTest(t, w, "m2")
+
+	if got := readTestFile(t, w.pathSoundData("1")); got != "active" {
+		t.Errorf("active sound = %q, want it unchanged until approval", got)
+	}
+	if got := readTestFile(t, w.pathPendingData("1")); got != "pending m2" {
+		t.Errorf("pending sound = %q, want the newer upload", got)
+	}
+	if p := w.repository.Pending["1"]; p == nil || p.MessageID != "m2" {
+		t.Errorf("pending = %+v, want m2", p)
+	}
+	if _, err := os.Stat(w.pathOriginalData(first)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("original of the replaced upload: stat error = %v, want it removed", err)
+	}
+}
+
+func TestApproveSound(t *testing.T) {
+	w := newModerationTest(t)
+	o := submitTest(t, w, "m1")
+
+	if err := w.approveSound("1", "g"); err != nil {
+		t.Fatalf("approveSound() error = %v", err)
+	}
+
+	if got := readTestFile(t, w.pathSoundData("1")); got != "pending m1" {
+		t.Errorf("active sound = %q, want the approved one", got)
+	}
+	if _, err := os.Stat(w.pathPendingData("1")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("pending file: stat error = %v, want it moved", err)
+	}
+	if _, ok := w.repository.Pending["1"]; ok {
+		t.Error("the sound is still pending")
+	}
+	if got := w.repository.Originals["1"]; got != o {
+		t.Errorf("original = %+v, want %+v", got, o)
+	}
+	if got := w.messageByUser["1"]; got != "m1" {
+		t.Errorf("message = %q, want m1", got)
+	}
+}
+
+func TestRejectSound(t *testing.T) {
+	w := newModerationTest(t)
+	o := submitTest(t, w, "m1")
+
+	if err := w.rejectSound("1", "too loud"); err != nil {
+		t.Fatalf("rejectSound() error = %v", err)
+	}
+
+	if got := readTestFile(t, w.pathSoundData("1")); got != "active" {
+		t.Errorf("active sound = %q, want it unchanged", got)
+	}
+	for _, p := range []string{w.pathPendingData("1"), w.pathOriginalData(o)} {
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: stat error = %v, want it removed", p, err)
+		}
+	}
+	if _, ok := w.repository.Pending["1"]; ok {
+		t.Error("the sound is still pending")
+	}
+
+	var rejectErr *RejectError
+	if err := w.rejectSound("1", ""); !errors.As(err, &rejectErr) {
+		t.Errorf("rejectSound() again error = %v, want a rejection", err)
+	}
+}
+
+func TestDropStalePending(t *testing.T) {
+	w := newModerationTest(t)
+	o := submitTest(t, w, "m1")
+	w.repository.Pending["2"] = &pendingSound{MessageID: "m2"}
+	if err := os.WriteFile(w.pathPendingData("2"), []byte("pending m2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w.dropStalePending(map[string]bool{"2": true})
+
+	if _, ok := w.repository.Pending["1"]; ok {
+		t.Error("the pending sound without a message is kept")
+	}
+	for _, p := range []string{w.pathPendingData("1"), w.pathOriginalData(o)} {
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: stat error = %v, want it removed", p, err)
+		}
+	}
+	if _, ok := w.repository.Pending["2"]; !ok {
+		t.Error("the pending sound with a message is dropped")
+	}
+	if _, err := os.Stat(w.pathPendingData("2")); err != nil {
+		t.Errorf("pending file with a message: %v", err)
+	}
+}
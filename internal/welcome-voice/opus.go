@@ -0,0 +1,152 @@
+package welcomevoice
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pion/webrtc/v4/pkg/media/oggreader"
+)
+
+const (
+	opusSampleRate = 48000
+	// opusFrameSamples is the number of samples in one 20ms frame, Discord
+	// expects exactly one such frame per packet.
+	opusFrameSamples = opusSampleRate / 50
+)
+
+type oggInfo struct {
+	Channels uint8
+	// SampleRate is the input sample rate from the Opus header, Opus itself
+	// always decodes at 48 kHz.
+	SampleRate uint32
+	Pages      int
+	Duration   time.Duration
+}
+
+// inspectOgg checks that the file is an Ogg Opus stream which can be sent
+// to Discord page by page, as play does.
+func inspectOgg(path string) (oggInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return oggInfo{}, fmt.Errorf("open: %w", err)
+	}
+	defer f.Close()
+
+	reader, header, err := oggreader.NewWith(bufio.NewReader(f))
+	if err != nil {
+		return oggInfo{}, fmt.Errorf("ogg reader: %w", err)
+	}
+
+	if header.Channels != 1 && header.Channels != 2 {
+		return oggInfo{}, fmt.Errorf("%d channels: %w", header.Channels, ErrIncompatibleOgg)
+	}
+	info := oggInfo{
+		Channels:   header.Channels,
+		SampleRate: header.SampleRate,
+	}
+
+	var (
+		granule uint64
+		// short is set when a page shorter than a frame is found, only the
+		// last page may be trimmed that way.
+		short bool
+	)
+	for {
+		data, page, err := reader.ParseNextPage()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return oggInfo{}, fmt.Errorf("parse page %d: %w", info.Pages, err)
+		}
+
+		// Comment header pages carry no audio.
+		if page.GranulePosition == 0 {
+			continue
+		}
+		if short {
+			return oggInfo{}, fmt.Errorf("page %d after a short page: %w", info.Pages, ErrIncompatibleOgg)
+		}
+
+		samples, ok := opusPacketSamples(data)
+		if !ok || samples != opusFrameSamples {
+			return oggInfo{}, fmt.Errorf("page %d is not a single 20ms packet: %w", info.Pages, ErrIncompatibleOgg)
+		}
+
+		// The packet check only sees the first packet of the page, the
+		// granule delta tells whether more follow. The first page counts
+		// from zero and includes the pre-skip.
+		delta := page.GranulePosition - granule
+		switch {
+		case page.GranulePosition < granule:
+			return oggInfo{}, fmt.Errorf("page %d granule goes back: %w", info.Pages, ErrIncompatibleOgg)
+		case delta == opusFrameSamples:
+		case delta < opusFrameSamples && info.Pages == 0:
+			// A single page stream, it must at least cover the pre-skip.
+			if delta < uint64(header.PreSkip) {
+				return oggInfo{}, fmt.Errorf("page %d ends within pre-skip: %w", info.Pages, ErrIncompatibleOgg)
+			}
+			short = true
+		case delta < opusFrameSamples:
+			// Only the last page may be trimmed.
+			short = true
+		default:
+			return oggInfo{}, fmt.Errorf("page %d holds %d samples: %w", info.Pages, delta, ErrIncompatibleOgg)
+		}
+
+		granule = page.GranulePosition
+		info.Pages++
+	}
+
+	if info.Pages == 0 {
+		return oggInfo{}, fmt.Errorf("no audio: %w", ErrIncompatibleOgg)
+	}
+
+	samples := granule - uint64(header.PreSkip)
+	if granule < uint64(header.PreSkip) {
+		samples = 0
+	}
+	info.Duration = time.Duration(samples) * time.Second / opusSampleRate
+
+	return info, nil
+}
+
+// opusPacketSamples returns the number of 48 kHz samples in the packet
+// according to its TOC byte (RFC 6716, section 3.1).
+func opusPacketSamples(packet []byte) (int, bool) {
+	if len(packet) < 1 {
+		return 0, false
+	}
+
+	toc := packet[0]
+	config := toc >> 3
+
+	var frame int // in 1/400 s units of 120 samples
+	switch {
+	case config < 12: // SILK: 10, 20, 40, 60 ms
+		frame = []int{4, 8, 16, 24}[config%4]
+	case config < 16: // Hybrid: 10, 20 ms
+		frame = []int{4, 8}[config%2]
+	default: // CELT: 2.5, 5, 10, 20 ms
+		frame = []int{1, 2, 4, 8}[config%4]
+	}
+
+	var frames int
+	switch toc & 0x03 {
+	case 0:
+		frames = 1
+	case 1, 2:
+		frames = 2
+	case 3:
+		if len(packet) < 2 {
+			return 0, false
+		}
+		frames = int(packet[1] & 0x3F)
+	}
+
+	return frame * frames * 120, true
+}
@@ -0,0 +1,76 @@
+package welcomevoice
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOpusPacketSamples(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		want   int
+		wantOK bool
+	}{
+		{name: "empty"},
+		{name: "celt 20ms", packet: []byte{31 << 3}, want: 960, wantOK: true},
+		{name: "celt 2.5ms", packet: []byte{16 << 3}, want: 120, wantOK: true},
+		{name: "silk 60ms", packet: []byte{3 << 3}, want: 2880, wantOK: true},
+		{name: "hybrid 10ms", packet: []byte{12 << 3}, want: 480, wantOK: true},
+		{name: "two frames", packet: []byte{31<<3 | 1}, want: 1920, wantOK: true},
+		{name: "arbitrary frames", packet: []byte{31<<3 | 3, 3}, want: 2880, wantOK: true},
+		{name: "arbitrary without count", packet: []byte{31<<3 | 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := opusPacketSamples(tt.packet)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("opusPacketSamples() = %d, %v, want %d, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestInspectOgg(t *testing.T) {
+	tests := []struct {
+		name     string
+		preSkip  uint16
+		granules []uint64
+		packets  []int
+		want     time.Duration
+		wantErr  bool
+	}{
+		{name: "frames", preSkip: 312, granules: []uint64{960, 1920, 2880}, want: (2880 - 312) * time.Second / opusSampleRate},
+		{name: "trimmed last page", preSkip: 312, granules: []uint64{960, 1920, 2400}, want: (2400 - 312) * time.Second / opusSampleRate},
+		{name: "single short page", preSkip: 312, granules: []uint64{700}, want: (700 - 312) * time.Second / opusSampleRate},
+		{name: "single page within pre-skip", preSkip: 312, granules: []uint64{200}, wantErr: true},
+		{name: "several packets on the first page", preSkip: 312, granules: []uint64{2880, 3840}, packets: []int{3, 1}, wantErr: true},
+		{name: "several packets on a later page", preSkip: 312, granules: []uint64{960, 2880}, packets: []int{1, 2}, wantErr: true},
+		{name: "short page in the middle", preSkip: 312, granules: []uint64{960, 1400, 2360}, wantErr: true},
+		{name: "granule goes back", preSkip: 312, granules: []uint64{1920, 960}, wantErr: true},
+		{name: "no audio", preSkip: 312, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := inspectOgg(writeOpus(t, tt.preSkip, tt.granules, tt.packets))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("inspectOgg() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrIncompatibleOgg) {
+					t.Errorf("inspectOgg() error = %v, want %v", err, ErrIncompatibleOgg)
+				}
+				return
+			}
+			if info.Duration != tt.want {
+				t.Errorf("inspectOgg() duration = %s, want %s", info.Duration, tt.want)
+			}
+			if info.Channels != 2 || info.SampleRate != opusSampleRate {
+				t.Errorf("inspectOgg() = %+v, want 2 channels at %d Hz", info, opusSampleRate)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package welcomevoice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"time"
+)
+
+const originalDir = "original"
+
+// originalSound is the file as uploaded by the user, kept to convert it
+// again when the encoder settings change.
+type originalSound struct {
+	MessageID string    `json:"message_id"`
+	Extension string    `json:"extension"`
+	Trim      trimRange `json:"trim"`
+	Size      int64     `json:"size"`
+	StoredAt  time.Time `json:"stored_at"`
+	Hash      string    `json:"hash,omitempty"`
+}
+
+func (w *WelcomeVoice) pathOriginalData(o *originalSound) string {
+	return path.Join(w.config.VoiceDir, originalDir, o.MessageID+o.Extension)
+}
+
+// storeOriginal copies the downloaded file to the store.
+func (w *WelcomeVoice) storeOriginal(messageID, from string, trim trimRange) (*originalSound, error) {
+	info, err := os.Stat(from)
+	if err != nil {
+		return nil, fmt.Errorf("stat: %w", err)
+	}
+
+	hash, err := hashFile(from)
+	if err != nil {
+		return nil, fmt.Errorf("hash: %w", err)
+	}
+
+	o := &originalSound{
+		MessageID: messageID,
+		Extension: path.Ext(from),
+		Trim:      trim,
+		Size:      info.Size(),
+		StoredAt:  time.Now(),
+		Hash:      hash,
+	}
+
+	if err := copyFile(from, w.pathOriginalData(o)); err != nil {
+		return nil, fmt.Errorf("copy: %w", err)
+	}
+
+	return o, nil
+}
+
+// setOriginal replaces the original of the user's active sound, nil drops it.
+func (w *WelcomeVoice) setOriginal(userID string, o *originalSound) {
+	if old, ok := w.repository.Originals[userID]; ok && (o == nil || old.MessageID != o.MessageID) {
+		w.removeOriginal(old)
+	}
+
+	if o == nil {
+		delete(w.repository.Originals, userID)
+	} else {
+		w.repository.Originals[userID] = o
+	}
+
+	w.enforceOriginalQuota()
+	w.saveRepository()
+}
+
+func (w *WelcomeVoice) removeOriginal(o *originalSound) {
+	if err := os.Remove(w.pathOriginalData(o)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		w.logger.Printf("remove original: %s", err.Error())
+	}
+}
+
+// enforceOriginalQuota drops the oldest originals of active sounds until
+// all originals fit into the quota. Those sounds can not be re-encoded.
+func (w *WelcomeVoice) enforceOriginalQuota() {
+	quota := w.config.OriginalQuotaMB * 1024 * 1024
+	if quota <= 0 {
+		return
+	}
+
+	var total int64
+	for _, o := range w.repository.Originals {
+		total += o.Size
+	}
+	for _, p := range w.repository.Pending {
+		if p.Original != nil {
+			total += p.Original.Size
+		}
+	}
+
+	userIDs := make([]string, 0, len(w.repository.Originals))
+	for userID := range w.repository.Originals {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool {
+		return w.repository.Originals[userIDs[i]].StoredAt.Before(w.repository.Originals[userIDs[j]].StoredAt)
+	})
+
+	for _, userID := range userIDs {
+		if total <= quota {
+			return
+		}
+
+		o := w.repository.Originals[userID]
+		w.removeOriginal(o)
+		delete(w.repository.Originals, userID)
+		total -= o.Size
+
+		w.logger.Printf("original quota: dropped the original of %s", userID)
+	}
+}
+
+// Reencode converts all active and pending sounds again from their original
+// uploads with the current settings, without a Discord connection. The bot
+// must not be running meanwhile, it keeps the repository in memory and would
+// overwrite the updated hashes.
+func Reencode(ctx context.Context, config Config, logger *log.Logger) error {
+	logger.SetPrefix("[Welcome Voice]: ")
+
+	w := &WelcomeVoice{
+		config: config,
+		logger: logger,
+		ctx:    ctx,
+	}
+
+	repo, err := w.readRepository()
+	if err != nil {
+		return fmt.Errorf("read repository: %w", err)
+	}
+	w.repository = repo
+
+	if err := os.MkdirAll(path.Join(config.VoiceDir, blobDir), os.ModePerm); err != nil {
+		return fmt.Errorf("mkdir blob dir: %w", err)
+	}
+
+	var (
+		done int
+		errs []error
+	)
+	reencode := func(userID string, o *originalSound, to string) bool {
+		if ctx.Err() != nil {
+			return false
+		}
+
+		if err := w.convertSound(ctx, w.pathOriginalData(o), to, o.Trim); err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", userID, err))
+			return false
+		}
+		done++
+
+		return true
+	}
+
+	for userID, o := range repo.Originals {
+		// The new file replaced the link to the blob, the hash changed.
+		if reencode(userID, o, w.pathSoundData(userID)) {
+			if err := w.dedupSound(userID); err != nil {
+				errs = append(errs, fmt.Errorf("user %s: dedup: %w", userID, err))
+			}
+		}
+	}
+	for userID, p := range repo.Pending {
+		if p.Original != nil {
+			reencode(userID, p.Original, w.pathPendingData(userID))
+		}
+	}
+
+	if err := w.writeRepository(); err != nil {
+		errs = append(errs, fmt.Errorf("write repository: %w", err))
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("interrupted: %w", err))
+	}
+
+	logger.Printf("reencode: %d sounds converted, %d failed", done, len(errs))
+
+	return errors.Join(errs...)
+}
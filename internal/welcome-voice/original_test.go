@@ -0,0 +1,68 @@
+package welcomevoice
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestReencodeUpdatesHashes(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{
+		// Passthrough copies the original, no ffmpeg is needed.
+		Convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true},
+	})
+
+	original := writeOpus(t, 312, []uint64{960, 1920}, nil)
+	o, err := w.storeOriginal("m1", original, trimRange{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.repository.Originals["1"] = o
+
+	if err := os.WriteFile(w.pathSoundData("1"), []byte("old sound"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.dedupSound("1"); err != nil {
+		t.Fatal(err)
+	}
+	oldHash := w.repository.Sounds["1"]
+
+	if err := Reencode(context.Background(), w.config, log.New(io.Discard, "", 0)); err != nil {
+		t.Fatalf("Reencode() error = %v", err)
+	}
+
+	repo, err := w.readRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := hashFile(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := repo.Sounds["1"]; got != want {
+		t.Errorf("hash = %s, want %s", got, want)
+	}
+	if _, err := os.Stat(w.pathBlobData(want)); err != nil {
+		t.Errorf("new blob: %v", err)
+	}
+	if _, err := os.Stat(w.pathBlobData(oldHash)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("old blob is kept: %v", err)
+	}
+}
+
+func TestReencodeCancelled(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{})
+	w.repository.Originals["1"] = &originalSound{MessageID: "m1", Extension: ".ogg"}
+	w.saveRepository()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := Reencode(ctx, w.config, log.New(io.Discard, "", 0)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Reencode() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,193 @@
+package welcomevoice
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+// preferences are the user's greeting settings.
+type preferences struct {
+	// NoListen skips greetings when the user is the only listener.
+	NoListen bool `json:"no_listen,omitempty"`
+	// NoGreet disables the user's own greeting.
+	NoGreet bool `json:"no_greet,omitempty"`
+	// Muted are users whose greetings the user does not want to hear.
+	Muted []string `json:"muted,omitempty"`
+}
+
+func (w *WelcomeVoice) preferences(userID string) *preferences {
+	p, ok := w.repository.Preferences[userID]
+	if !ok {
+		p = &preferences{}
+		w.repository.Preferences[userID] = p
+	}
+
+	return p
+}
+
+// channelListeners returns users in the voice channel except the bot.
+func (w *WelcomeVoice) channelListeners(guildID, channelID string) []string {
+	var listeners []string
+
+	guild, err := w.client.State.Guild(guildID)
+	if err == nil {
+		w.client.State.RLock()
+		for _, vs := range guild.VoiceStates {
+			if vs.ChannelID == channelID && vs.UserID != w.client.State.User.ID {
+				listeners = append(listeners, vs.UserID)
+			}
+		}
+		w.client.State.RUnlock()
+
+		return listeners
+	}
+
+	for userID, ch := range w.channelByUser {
+		if ch == channelID {
+			listeners = append(listeners, userID)
+		}
+	}
+
+	return listeners
+}
+
+// wantsGreeting reports whether anyone in the channel wants to hear the
+// user's greeting. Alone in the channel, the user who joined decides.
+func (w *WelcomeVoice) wantsGreeting(userID string, listeners []string) bool {
+	if p, ok := w.repository.Preferences[userID]; ok && p.NoGreet {
+		return false
+	}
+
+	others := slices.DeleteFunc(slices.Clone(listeners), func(listener string) bool {
+		return listener == userID
+	})
+	if len(others) == 0 {
+		p, ok := w.repository.Preferences[userID]
+		return !ok || !p.NoListen
+	}
+
+	for _, listener := range others {
+		p, ok := w.repository.Preferences[listener]
+		if !ok {
+			return true
+		}
+		if p.NoListen || slices.Contains(p.Muted, userID) {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func parseSwitch(args []string, usage string) (bool, error) {
+	if len(args) < 1 {
+		return false, reject("Use `%s`.", usage)
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	default:
+		return false, reject("Use `%s`.", usage)
+	}
+}
+
+func (w *WelcomeVoice) commandListen(c *commandContext, args []string) (*discordgo.MessageSend, error) {
+	on, err := parseSwitch(args, w.config.CommandPrefix+" listen on|off")
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.preferences(c.Author.ID).NoListen = !on
+	w.saveRepository()
+
+	if on {
+		return textReply("You will hear greetings again."), nil
+	}
+
+	return textReply("Greetings are skipped when nobody else wants to hear them."), nil
+}
+
+func (w *WelcomeVoice) commandGreet(c *commandContext, args []string) (*discordgo.MessageSend, error) {
+	on, err := parseSwitch(args, w.config.CommandPrefix+" greet on|off")
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.preferences(c.Author.ID).NoGreet = !on
+	w.saveRepository()
+
+	if on {
+		return textReply("Your greeting is played again when you join."), nil
+	}
+
+	return textReply("Your greeting is not played anymore."), nil
+}
+
+func (w *WelcomeVoice) commandMute(c *commandContext, args []string) (*discordgo.MessageSend, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	p := w.preferences(c.Author.ID)
+
+	if len(args) == 0 {
+		if len(p.Muted) == 0 {
+			return textReply("You have not muted anyone."), nil
+		}
+
+		var b strings.Builder
+		b.WriteString("Muted greetings:")
+		for _, userID := range p.Muted {
+			fmt.Fprintf(&b, " <@%s>", userID)
+		}
+
+		return textReply("%s", b.String()), nil
+	}
+
+	userID, err := parseUser(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(p.Muted, userID) {
+		p.Muted = append(p.Muted, userID)
+		w.saveRepository()
+	}
+
+	return textReply("You will not hear the greeting of <@%s>.", userID), nil
+}
+
+func (w *WelcomeVoice) commandUnmute(c *commandContext, args []string) (*discordgo.MessageSend, error) {
+	if len(args) < 1 {
+		return nil, reject("Whose greeting should be unmuted?")
+	}
+
+	userID, err := parseUser(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	p := w.preferences(c.Author.ID)
+	if i := slices.Index(p.Muted, userID); i != -1 {
+		p.Muted = slices.Delete(p.Muted, i, i+1)
+		w.saveRepository()
+	}
+
+	return textReply("You will hear the greeting of <@%s> again.", userID), nil
+}
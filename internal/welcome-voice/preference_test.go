@@ -0,0 +1,91 @@
+package welcomevoice
+
+import "testing"
+
+func TestWantsGreeting(t *testing.T) {
+	tests := []struct {
+		name        string
+		preferences map[string]*preferences
+		listeners   []string
+		want        bool
+	}{
+		{
+			name:      "no preferences",
+			listeners: []string{"1", "2"},
+			want:      true,
+		},
+		{
+			name:        "joiner alone",
+			preferences: map[string]*preferences{},
+			listeners:   []string{"1"},
+			want:        true,
+		},
+		{
+			name:        "joiner alone does not listen",
+			preferences: map[string]*preferences{"1": {NoListen: true}},
+			listeners:   []string{"1"},
+			want:        false,
+		},
+		{
+			name:        "joiner not listed",
+			preferences: map[string]*preferences{},
+			listeners:   nil,
+			want:        true,
+		},
+		{
+			name:        "joiner does not greet",
+			preferences: map[string]*preferences{"1": {NoGreet: true}},
+			listeners:   []string{"1", "2"},
+			want:        false,
+		},
+		{
+			name:        "only listener muted the joiner",
+			preferences: map[string]*preferences{"2": {Muted: []string{"1"}}},
+			listeners:   []string{"1", "2"},
+			want:        false,
+		},
+		{
+			name:        "muted by one listener",
+			preferences: map[string]*preferences{"2": {Muted: []string{"1"}}},
+			listeners:   []string{"1", "2", "3"},
+			want:        true,
+		},
+		{
+			name:        "mute of someone else",
+			preferences: map[string]*preferences{"2": {Muted: []string{"3"}}},
+			listeners:   []string{"1", "2"},
+			want:        true,
+		},
+		{
+			name: "everyone else does not listen",
+			preferences: map[string]*preferences{
+				"2": {NoListen: true},
+				"3": {NoListen: true},
+			},
+			listeners: []string{"1", "2", "3"},
+			want:      false,
+		},
+		{
+			name: "joiner does not listen, others do",
+			preferences: map[string]*preferences{
+				"1": {NoListen: true},
+				"2": {},
+			},
+			listeners: []string{"1", "2"},
+			want:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWelcomeVoice(t, Config{})
+			if tt.preferences != nil {
+				w.repository.Preferences = tt.preferences
+			}
+
+			if got := w.wantsGreeting("1", tt.listeners); got != tt.want {
+				t.Errorf("wantsGreeting() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
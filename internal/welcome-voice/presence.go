@@ -0,0 +1,75 @@
+package welcomevoice
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/exp/slices"
+)
+
+type PresenceConfig struct {
+	// MinListeners is the number of other users who must be in the channel.
+	MinListeners int `json:"min_listeners,omitempty"`
+	// AllowChannels limits greetings to the channels, all if empty.
+	AllowChannels []string `json:"allow_channels,omitempty"`
+	DenyChannels  []string `json:"deny_channels,omitempty"`
+	SkipAFK       bool     `json:"skip_afk,omitempty"`
+	SkipStage     bool     `json:"skip_stage,omitempty"`
+	// SkipStreaming skips channels where someone is streaming.
+	SkipStreaming bool `json:"skip_streaming,omitempty"`
+}
+
+// presenceSkip returns why the greeting should not be played in the channel,
+// or an empty string if it should.
+func (w *WelcomeVoice) presenceSkip(userID, guildID, channelID string) string {
+	c := w.config.Presence
+
+	if len(c.AllowChannels) > 0 && !slices.Contains(c.AllowChannels, channelID) {
+		return "channel is not allowed"
+	}
+	if slices.Contains(c.DenyChannels, channelID) {
+		return "channel is denied"
+	}
+
+	if c.SkipStage {
+		if ch, err := w.client.State.Channel(channelID); err == nil && ch.Type == discordgo.ChannelTypeGuildStageVoice {
+			return "stage channel"
+		}
+	}
+
+	guild, err := w.client.State.Guild(guildID)
+	if err != nil {
+		// Without the state only the listeners tracked here can be checked.
+		if others := len(w.channelListeners(guildID, channelID)) - 1; others < c.MinListeners {
+			return fmt.Sprintf("%d listeners", others)
+		}
+
+		return ""
+	}
+
+	if c.SkipAFK && guild.AfkChannelID == channelID {
+		return "afk channel"
+	}
+
+	w.client.State.RLock()
+	defer w.client.State.RUnlock()
+
+	var others int
+	for _, vs := range guild.VoiceStates {
+		if vs.ChannelID != channelID || vs.UserID == w.client.State.User.ID {
+			continue
+		}
+		if c.SkipStreaming && vs.SelfStream {
+			return "someone is streaming"
+		}
+		if vs.UserID != userID {
+			others++
+		}
+	}
+
+	if others < c.MinListeners {
+		return fmt.Sprintf("%d listeners", others)
+	}
+
+	return ""
+}
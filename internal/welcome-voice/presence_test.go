@@ -0,0 +1,109 @@
+package welcomevoice
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPresenceSkip(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    PresenceConfig
+		channelID string
+		voice     []*discordgo.VoiceState
+		want      string
+	}{
+		{name: "no rules", channelID: "v1"},
+		{name: "allowed", config: PresenceConfig{AllowChannels: []string{"v1"}}, channelID: "v1"},
+		{name: "not allowed", config: PresenceConfig{AllowChannels: []string{"v2"}}, channelID: "v1", want: "channel is not allowed"},
+		{name: "denied", config: PresenceConfig{DenyChannels: []string{"v1"}}, channelID: "v1", want: "channel is denied"},
+		{name: "afk", config: PresenceConfig{SkipAFK: true}, channelID: "afk", want: "afk channel"},
+		{name: "afk allowed", channelID: "afk"},
+		{name: "stage", config: PresenceConfig{SkipStage: true}, channelID: "stage", want: "stage channel"},
+		{name: "stage allowed", channelID: "stage"},
+		{
+			name:      "streaming",
+			config:    PresenceConfig{SkipStreaming: true},
+			channelID: "v1",
+			voice:     []*discordgo.VoiceState{{UserID: "2", ChannelID: "v1", SelfStream: true}},
+			want:      "someone is streaming",
+		},
+		{
+			name:      "streaming elsewhere",
+			config:    PresenceConfig{SkipStreaming: true},
+			channelID: "v1",
+			voice:     []*discordgo.VoiceState{{UserID: "2", ChannelID: "v2", SelfStream: true}},
+		},
+		{
+			name:      "enough listeners",
+			config:    PresenceConfig{MinListeners: 1},
+			channelID: "v1",
+			voice:     []*discordgo.VoiceState{{UserID: "2", ChannelID: "v1"}},
+		},
+		{
+			name:      "too few listeners",
+			config:    PresenceConfig{MinListeners: 2},
+			channelID: "v1",
+			voice: []*discordgo.VoiceState{
+				{UserID: "2", ChannelID: "v1"},
+				{UserID: "3", ChannelID: "v2"},
+			},
+			want: "1 listeners",
+		},
+		{
+			name:      "the joining user and the bot are not listeners",
+			config:    PresenceConfig{MinListeners: 1},
+			channelID: "v1",
+			voice: []*discordgo.VoiceState{
+				{UserID: "1", ChannelID: "v1"},
+				{UserID: "bot", ChannelID: "v1"},
+			},
+			want: "0 listeners",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newTestWelcomeVoice(t, Config{Presence: tt.config})
+			w.client = offlineSession(t)
+
+			voice := append([]*discordgo.VoiceState{{UserID: "1", ChannelID: tt.channelID}}, tt.voice...)
+			for _, vs := range voice {
+				vs.GuildID = "g"
+			}
+			if err := w.client.State.GuildAdd(&discordgo.Guild{
+				ID:           "g",
+				AfkChannelID: "afk",
+				Channels: []*discordgo.Channel{
+					{ID: "v1", GuildID: "g", Type: discordgo.ChannelTypeGuildVoice},
+					{ID: "v2", GuildID: "g", Type: discordgo.ChannelTypeGuildVoice},
+					{ID: "afk", GuildID: "g", Type: discordgo.ChannelTypeGuildVoice},
+					{ID: "stage", GuildID: "g", Type: discordgo.ChannelTypeGuildStageVoice},
+				},
+				VoiceStates: voice,
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := w.presenceSkip("1", "g", tt.channelID); got != tt.want {
+				t.Errorf("presenceSkip() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPresenceSkipWithoutState(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{Presence: PresenceConfig{MinListeners: 1}})
+	w.client = offlineSession(t)
+
+	w.channelByUser["1"] = "v1"
+	if got := w.presenceSkip("1", "g", "v1"); got != "0 listeners" {
+		t.Errorf("presenceSkip() alone = %q, want 0 listeners", got)
+	}
+
+	w.channelByUser["2"] = "v1"
+	if got := w.presenceSkip("1", "g", "v1"); got != "" {
+		t.Errorf("presenceSkip() with a listener = %q, want none", got)
+	}
+}
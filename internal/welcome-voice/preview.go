@@ -0,0 +1,177 @@
+package welcomevoice
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	waveformWidth  = 800
+	waveformHeight = 160
+	// waveformRate is the sample rate the sound is decoded at, enough to
+	// draw the envelope.
+	waveformRate = 8000
+)
+
+var (
+	waveformBackground = color.RGBA{R: 0x2b, G: 0x2d, B: 0x31, A: 0xff}
+	waveformPeak       = color.RGBA{R: 0x58, G: 0x65, B: 0xf2, A: 0xff}
+	waveformRMS        = color.RGBA{R: 0xa5, G: 0xad, B: 0xf8, A: 0xff}
+)
+
+// postPreview replies to the message with the waveform, duration and
+// loudness of the user's active sound. It must be called with w.mu held,
+// the rendering runs in the background.
+func (w *WelcomeVoice) postPreview(userID string, m *discordgo.Message) {
+	if w.config.DisablePreview {
+		return
+	}
+
+	data, err := os.ReadFile(w.pathSoundData(userID))
+	if err != nil {
+		w.logger.Printf("preview: read sound: %s", err.Error())
+		return
+	}
+
+	go func() {
+		send, err := w.renderPreview(w.ctx, data)
+		if err != nil {
+			w.logger.Printf("preview: %s", err.Error())
+			return
+		}
+		send.Reference = m.Reference()
+
+		reply, err := w.client.ChannelMessageSendComplex(m.ChannelID, send)
+		if err != nil {
+			w.logger.Printf("preview: send: %s", err.Error())
+			return
+		}
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		if old, ok := w.repository.Previews[userID]; ok {
+			if err := w.client.ChannelMessageDelete(m.ChannelID, old); err != nil {
+				w.logger.Printf("preview: remove old: %s", err.Error())
+			}
+		}
+		w.repository.Previews[userID] = reply.ID
+		w.saveRepository()
+	}()
+}
+
+func (w *WelcomeVoice) renderPreview(ctx context.Context, data []byte) (*discordgo.MessageSend, error) {
+	temp, err := writeTemp(data)
+	if err != nil {
+		return nil, fmt.Errorf("write temp: %w", err)
+	}
+	defer os.Remove(temp)
+
+	samples, err := decodePCM(ctx, temp)
+	if err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, renderWaveform(samples)); err != nil {
+		return nil, fmt.Errorf("encode png: %w", err)
+	}
+
+	var b strings.Builder
+	if ogg, err := inspectOgg(temp); err == nil {
+		fmt.Fprintf(&b, "Duration: %s\n", ogg.Duration.Round(10*time.Millisecond))
+	}
+	if lufs, err := measureLoudness(ctx, temp); err == nil {
+		fmt.Fprintf(&b, "Loudness: %.1f LUFS\n", lufs)
+	} else {
+		w.logger.Printf("preview: measure loudness: %s", err.Error())
+	}
+
+	return &discordgo.MessageSend{
+		Content: strings.TrimSpace(b.String()),
+		Files: []*discordgo.File{{
+			Name:        "waveform.png",
+			ContentType: "image/png",
+			Reader:      &img,
+		}},
+	}, nil
+}
+
+// decodePCM returns the sound as mono samples at waveformRate.
+func decodePCM(ctx context.Context, path string) ([]int16, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg", "-v", "error", "-i", path,
+		"-ac", "1", "-ar", fmt.Sprint(waveformRate), "-f", "s16le", "-")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg: %s, %w", stderr.String(), err)
+	}
+
+	samples := make([]int16, len(output)/2)
+	if err := binary.Read(bytes.NewReader(output[:len(samples)*2]), binary.LittleEndian, samples); err != nil {
+		return nil, fmt.Errorf("read samples: %w", err)
+	}
+
+	return samples, nil
+}
+
+// renderWaveform draws the peak and RMS envelope of the samples, one column
+// per slice of the sound.
+func renderWaveform(samples []int16) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, waveformWidth, waveformHeight))
+	for y := 0; y < waveformHeight; y++ {
+		for x := 0; x < waveformWidth; x++ {
+			img.SetRGBA(x, y, waveformBackground)
+		}
+	}
+
+	if len(samples) == 0 {
+		return img
+	}
+
+	mid := waveformHeight / 2
+	for x := 0; x < waveformWidth; x++ {
+		from := x * len(samples) / waveformWidth
+		to := (x + 1) * len(samples) / waveformWidth
+		if to <= from {
+			to = from + 1
+		}
+
+		var (
+			peak float64
+			sum  float64
+		)
+		for _, s := range samples[from:to] {
+			v := math.Abs(float64(s)) / math.MaxInt16
+			peak = math.Max(peak, v)
+			sum += v * v
+		}
+		rms := math.Sqrt(sum / float64(to-from))
+
+		drawColumn(img, x, mid, int(peak*float64(mid)), waveformPeak)
+		drawColumn(img, x, mid, int(rms*float64(mid)), waveformRMS)
+	}
+
+	return img
+}
+
+func drawColumn(img *image.RGBA, x, mid, half int, c color.RGBA) {
+	for y := mid - half; y <= mid+half; y++ {
+		img.SetRGBA(x, y, c)
+	}
+}
@@ -0,0 +1,83 @@
+package welcomevoice
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestRenderWaveform(t *testing.T) {
+	mid := waveformHeight / 2
+	// Every other sample at full scale: the peak reaches the edges, the RMS
+	// of 1/√2 stays inside.
+	rmsHalf := int(math.Sqrt(0.5) * float64(mid))
+
+	alternating := make([]int16, 2*waveformWidth)
+	for i := 0; i < len(alternating); i += 2 {
+		alternating[i] = math.MaxInt16
+	}
+
+	tests := []struct {
+		name    string
+		samples []int16
+		pixels  map[image.Point]color.RGBA
+	}{
+		{
+			name: "no samples",
+			pixels: map[image.Point]color.RGBA{
+				{0, mid}:                 waveformBackground,
+				{waveformWidth - 1, mid}: waveformBackground,
+			},
+		},
+		{
+			name:    "peak and rms",
+			samples: alternating,
+			pixels: map[image.Point]color.RGBA{
+				{0, 0}:                             waveformPeak,
+				{0, mid - rmsHalf - 1}:             waveformPeak,
+				{0, mid - rmsHalf}:                 waveformRMS,
+				{0, mid}:                           waveformRMS,
+				{waveformWidth - 1, 0}:             waveformPeak,
+				{waveformWidth - 1, mid + rmsHalf}: waveformRMS,
+			},
+		},
+		{
+			// Two samples for the whole width: the left half shows the loud
+			// one, the right half the silent one.
+			name:    "fewer samples than columns",
+			samples: []int16{math.MaxInt16, 0},
+			pixels: map[image.Point]color.RGBA{
+				{0, 0}:                   waveformRMS,
+				{waveformWidth/2 - 1, 0}: waveformRMS,
+				{waveformWidth / 2, 0}:   waveformBackground,
+				{waveformWidth / 2, mid}: waveformRMS,
+				{waveformWidth - 1, 0}:   waveformBackground,
+				{waveformWidth - 1, mid}: waveformRMS,
+			},
+		},
+		{
+			name:    "single sample",
+			samples: []int16{math.MinInt16 + 1},
+			pixels: map[image.Point]color.RGBA{
+				{0, 0}:                 waveformRMS,
+				{waveformWidth - 1, 0}: waveformRMS,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := renderWaveform(tt.samples)
+			if got := img.Bounds(); got != image.Rect(0, 0, waveformWidth, waveformHeight) {
+				t.Fatalf("bounds = %v, want %dx%d", got, waveformWidth, waveformHeight)
+			}
+
+			for p, want := range tt.pixels {
+				if got := color.RGBAModel.Convert(img.At(p.X, p.Y)); got != want {
+					t.Errorf("pixel %v = %v, want %v", p, got, want)
+				}
+			}
+		})
+	}
+}
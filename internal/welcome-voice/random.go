@@ -0,0 +1,168 @@
+package welcomevoice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/tekig/mog-go/internal/duration"
+)
+
+const (
+	RandomProviderLocal = "local"
+	RandomProviderHTTP  = "http"
+
+	defaultRandomTimeout = 10 * time.Second
+)
+
+// RandomSoundProvider picks a sound for users who have not uploaded one.
+type RandomSoundProvider interface {
+	RandomSound(ctx context.Context) (*RandomSound, error)
+}
+
+type RandomSound struct {
+	// Path is a local file with the sound, it may need conversion.
+	Path string
+	// Source tells where the sound came from.
+	Source string
+
+	temporary bool
+}
+
+// Close releases the sound file.
+func (s *RandomSound) Close() error {
+	if !s.temporary {
+		return nil
+	}
+
+	return os.Remove(s.Path)
+}
+
+type RandomProviderConfig struct {
+	Type    string            `json:"type,omitempty"`
+	Dir     string            `json:"dir,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	Timeout duration.Duration `json:"timeout,omitempty"`
+	Retries int               `json:"retries,omitempty"`
+}
+
+// NewRandomSoundProvider builds providers from the config. Several providers
+// are tried in order until one of them succeeds.
+func NewRandomSoundProvider(configs []RandomProviderConfig) (RandomSoundProvider, error) {
+	var providers ChainProvider
+	for i, c := range configs {
+		switch c.Type {
+		case RandomProviderLocal:
+			providers = append(providers, &LocalProvider{Dir: c.Dir})
+		case RandomProviderHTTP:
+			timeout := c.Timeout.Duration
+			if timeout == 0 {
+				timeout = defaultRandomTimeout
+			}
+
+			providers = append(providers, &HTTPProvider{
+				URL:     c.URL,
+				Client:  &http.Client{Timeout: timeout},
+				Retries: c.Retries,
+			})
+		default:
+			return nil, fmt.Errorf("provider #%d %q: %w", i, c.Type, ErrUnknownProvider)
+		}
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+
+	return providers, nil
+}
+
+// LocalProvider picks a random file from a directory of prepared sounds.
+type LocalProvider struct {
+	Dir string
+}
+
+func (p *LocalProvider) RandomSound(_ context.Context) (*RandomSound, error) {
+	entries, err := os.ReadDir(p.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+
+	var names []string
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			names = append(names, e.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s: %w", p.Dir, ErrNoRandomSound)
+	}
+
+	name := names[rand.Intn(len(names))]
+
+	return &RandomSound{
+		Path:   path.Join(p.Dir, name),
+		Source: "local:" + name,
+	}, nil
+}
+
+// HTTPProvider downloads a sound from an endpoint returning a random file.
+type HTTPProvider struct {
+	URL     string
+	Client  *http.Client
+	Retries int
+}
+
+func (p *HTTPProvider) RandomSound(ctx context.Context) (*RandomSound, error) {
+	var errs []error
+	for attempt := 0; attempt <= p.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * time.Second):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		path, err := download(ctx, p.Client, p.URL)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("attempt #%d: %w", attempt, err))
+			continue
+		}
+
+		return &RandomSound{
+			Path:      path,
+			Source:    p.URL,
+			temporary: true,
+		}, nil
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// ChainProvider asks providers in order and returns the first sound found.
+type ChainProvider []RandomSoundProvider
+
+func (c ChainProvider) RandomSound(ctx context.Context) (*RandomSound, error) {
+	var errs []error
+	for i, p := range c {
+		sound, err := p.RandomSound(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("provider #%d: %w", i, err))
+			continue
+		}
+
+		return sound, nil
+	}
+
+	if len(errs) == 0 {
+		return nil, ErrNoRandomSound
+	}
+
+	return nil, errors.Join(errs...)
+}
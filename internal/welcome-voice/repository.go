@@ -0,0 +1,127 @@
+package welcomevoice
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+)
+
+const (
+	storageName    = "sounds.json"
+	tmpStorageName = "sounds.json.tmp"
+	bakStorageName = "sounds.json.bak"
+)
+
+// repository is the metadata of stored sounds, the sounds themselves are
+// kept as files next to it.
+type repository struct {
+	Fallbacks   map[string]*fallbackSound  `json:"fallbacks,omitempty"`
+	Pending     map[string]*pendingSound   `json:"pending,omitempty"`
+	History     map[string][]*soundVersion `json:"history,omitempty"`
+	Originals   map[string]*originalSound  `json:"originals,omitempty"`
+	Preferences map[string]*preferences    `json:"preferences,omitempty"`
+	// Previews is the waveform reply to every user's active sound.
+	Previews map[string]string `json:"previews,omitempty"`
+	// Sounds is the content hash of every user's active sound.
+	Sounds map[string]string `json:"sounds,omitempty"`
+	Banned []*bannedSound    `json:"banned,omitempty"`
+	// Imports are the active sounds loaded from an archive.
+	Imports map[string]*importedSound `json:"imports,omitempty"`
+	// Playbacks are the latest playbacks, UserStats and SoundStats the
+	// totals by user and by sound content hash.
+	Playbacks  []*playbackRecord     `json:"playbacks,omitempty"`
+	UserStats  map[string]*playStats `json:"user_stats,omitempty"`
+	SoundStats map[string]*playStats `json:"sound_stats,omitempty"`
+}
+
+func newRepository() *repository {
+	return &repository{
+		Fallbacks:   make(map[string]*fallbackSound),
+		Pending:     make(map[string]*pendingSound),
+		History:     make(map[string][]*soundVersion),
+		Originals:   make(map[string]*originalSound),
+		Preferences: make(map[string]*preferences),
+		Previews:    make(map[string]string),
+		Sounds:      make(map[string]string),
+		Imports:     make(map[string]*importedSound),
+		UserStats:   make(map[string]*playStats),
+		SoundStats:  make(map[string]*playStats),
+	}
+}
+
+func (w *WelcomeVoice) readRepository() (*repository, error) {
+	readByName := func(name string) (*repository, error) {
+		f, err := os.Open(path.Join(w.config.VoiceDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("open: %w", err)
+		}
+		defer f.Close()
+
+		repo := newRepository()
+		if err := json.NewDecoder(f).Decode(repo); err != nil {
+			return nil, fmt.Errorf("decode: %w", err)
+		}
+
+		return repo, nil
+	}
+
+	repo, err := readByName(storageName)
+	if err != nil {
+		w.logger.Printf("read storage: %s", err.Error())
+
+		repo, err = readByName(bakStorageName)
+		if err != nil {
+			return nil, fmt.Errorf("read backup storage: %w", err)
+		}
+	}
+
+	return repo, nil
+}
+
+// writeRepository must be called with w.mu held.
+func (w *WelcomeVoice) writeRepository() error {
+	temp := path.Join(w.config.VoiceDir, tmpStorageName)
+
+	if err := func() error {
+		f, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+		if err != nil {
+			return fmt.Errorf("open temp storage: %w", err)
+		}
+		defer f.Close()
+
+		if err := json.NewEncoder(f).Encode(w.repository); err != nil {
+			return fmt.Errorf("encode: %w", err)
+		}
+
+		return nil
+	}(); err != nil {
+		return err
+	}
+
+	backup := path.Join(w.config.VoiceDir, bakStorageName)
+
+	if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove backup: %w", err)
+	}
+
+	primary := path.Join(w.config.VoiceDir, storageName)
+
+	if err := os.Rename(primary, backup); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("move primary to backup: %w", err)
+	}
+
+	if err := os.Rename(temp, primary); err != nil {
+		return fmt.Errorf("move temp to primary: %w", err)
+	}
+
+	return nil
+}
+
+// saveRepository writes the repository and only logs failures, the state in
+// memory stays authoritative until the next successful write.
+func (w *WelcomeVoice) saveRepository() {
+	if err := w.writeRepository(); err != nil {
+		w.logger.Printf("write repository: %s", err.Error())
+	}
+}
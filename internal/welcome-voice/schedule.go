@@ -0,0 +1,258 @@
+package welcomevoice
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	ScheduleSkip   = "skip"
+	ScheduleVolume = "volume"
+)
+
+type ScheduleConfig struct {
+	// Timezone is the IANA name used by rules without their own, the local
+	// time zone of the host by default.
+	Timezone string          `json:"timezone,omitempty"`
+	Rules    []ScheduleRule  `json:"rules,omitempty"`
+	Holidays []HolidayConfig `json:"holidays,omitempty"`
+}
+
+// ScheduleRule changes greetings during the time of day on the days. The
+// first matching rule applies.
+type ScheduleRule struct {
+	// GuildID limits the rule to one guild.
+	GuildID  string `json:"guild_id,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	// Days are "mon".."sun", "weekday" or "weekend", every day if empty.
+	Days []string `json:"days,omitempty"`
+	// From and To are "15:04" times, To before From spans midnight.
+	From   string  `json:"from,omitempty"`
+	To     string  `json:"to,omitempty"`
+	Action string  `json:"action,omitempty"`
+	Volume float64 `json:"volume,omitempty"`
+}
+
+// HolidayConfig replaces the random sounds between the dates.
+type HolidayConfig struct {
+	Name string `json:"name,omitempty"`
+	// From and To are "01-02" dates, To before From spans the new year.
+	From   string                 `json:"from,omitempty"`
+	To     string                 `json:"to,omitempty"`
+	Random []RandomProviderConfig `json:"random,omitempty"`
+}
+
+type scheduleRule struct {
+	guildID  string
+	location *time.Location
+	days     []time.Weekday
+	from, to time.Duration
+	action   string
+	volume   float64
+}
+
+type holiday struct {
+	name     string
+	from, to int // month*100 + day
+	random   RandomSoundProvider
+}
+
+type schedule struct {
+	location *time.Location
+	rules    []scheduleRule
+	holidays []holiday
+}
+
+func newSchedule(config ScheduleConfig) (*schedule, error) {
+	s := &schedule{location: time.Local}
+
+	if config.Timezone != "" {
+		loc, err := time.LoadLocation(config.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("timezone: %w", err)
+		}
+		s.location = loc
+	}
+
+	for i, r := range config.Rules {
+		rule, err := newScheduleRule(r, s.location)
+		if err != nil {
+			return nil, fmt.Errorf("rule #%d: %w", i, err)
+		}
+
+		s.rules = append(s.rules, rule)
+	}
+
+	for i, h := range config.Holidays {
+		from, err := parseMonthDay(h.From)
+		if err != nil {
+			return nil, fmt.Errorf("holiday #%d from: %w", i, err)
+		}
+		to, err := parseMonthDay(h.To)
+		if err != nil {
+			return nil, fmt.Errorf("holiday #%d to: %w", i, err)
+		}
+
+		if len(h.Random) == 0 {
+			return nil, fmt.Errorf("holiday #%d has no random providers: %w", i, ErrInvalidSchedule)
+		}
+
+		random, err := NewRandomSoundProvider(h.Random)
+		if err != nil {
+			return nil, fmt.Errorf("holiday #%d random: %w", i, err)
+		}
+
+		s.holidays = append(s.holidays, holiday{
+			name:   h.Name,
+			from:   from,
+			to:     to,
+			random: random,
+		})
+	}
+
+	return s, nil
+}
+
+func newScheduleRule(r ScheduleRule, location *time.Location) (scheduleRule, error) {
+	rule := scheduleRule{
+		guildID:  r.GuildID,
+		location: location,
+		action:   r.Action,
+		volume:   r.Volume,
+	}
+
+	switch r.Action {
+	case ScheduleSkip:
+	case ScheduleVolume:
+		if r.Volume <= 0 || r.Volume > 1 {
+			return rule, fmt.Errorf("volume %v: %w", r.Volume, ErrInvalidSchedule)
+		}
+	default:
+		return rule, fmt.Errorf("action %q: %w", r.Action, ErrInvalidSchedule)
+	}
+
+	if r.Timezone != "" {
+		loc, err := time.LoadLocation(r.Timezone)
+		if err != nil {
+			return rule, fmt.Errorf("timezone: %w", err)
+		}
+		rule.location = loc
+	}
+
+	for _, d := range r.Days {
+		days, ok := weekdays[strings.ToLower(d)]
+		if !ok {
+			return rule, fmt.Errorf("day %q: %w", d, ErrInvalidSchedule)
+		}
+		rule.days = append(rule.days, days...)
+	}
+
+	var err error
+	if rule.from, err = parseClock(r.From); err != nil {
+		return rule, fmt.Errorf("from: %w", err)
+	}
+	if rule.to, err = parseClock(r.To); err != nil {
+		return rule, fmt.Errorf("to: %w", err)
+	}
+
+	return rule, nil
+}
+
+var weekdays = map[string][]time.Weekday{
+	"mon":     {time.Monday},
+	"tue":     {time.Tuesday},
+	"wed":     {time.Wednesday},
+	"thu":     {time.Thursday},
+	"fri":     {time.Friday},
+	"sat":     {time.Saturday},
+	"sun":     {time.Sunday},
+	"weekday": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekend": {time.Saturday, time.Sunday},
+}
+
+// parseClock parses "15:04" into the time since midnight, empty is midnight.
+func parseClock(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q: %w", s, ErrInvalidSchedule)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func parseMonthDay(s string) (int, error) {
+	t, err := time.Parse("01-02", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q: %w", s, ErrInvalidSchedule)
+	}
+
+	return int(t.Month())*100 + t.Day(), nil
+}
+
+// matches reports whether the rule applies at the moment. A night span
+// belongs to the day it starts on.
+func (r scheduleRule) matches(guildID string, now time.Time) bool {
+	if r.guildID != "" && r.guildID != guildID {
+		return false
+	}
+
+	now = now.In(r.location)
+	y, m, d := now.Date()
+	clock := now.Sub(time.Date(y, m, d, 0, 0, 0, 0, r.location))
+	day := now.Weekday()
+
+	var inside bool
+	switch {
+	case r.from == r.to:
+		inside = true
+	case r.from < r.to:
+		inside = clock >= r.from && clock < r.to
+	case clock >= r.from:
+		inside = true
+	case clock < r.to:
+		inside = true
+		day = (day + 6) % 7
+	}
+
+	return inside && (len(r.days) == 0 || slices.Contains(r.days, day))
+}
+
+// greetingVolume returns the volume of a greeting at the moment, zero means
+// the greeting is skipped.
+func (s *schedule) greetingVolume(guildID string, now time.Time) float64 {
+	for _, r := range s.rules {
+		if !r.matches(guildID, now) {
+			continue
+		}
+
+		if r.action == ScheduleSkip {
+			return 0
+		}
+
+		return r.volume
+	}
+
+	return 1
+}
+
+// activeHoliday returns the holiday at the moment, if any.
+func (s *schedule) activeHoliday(now time.Time) (*holiday, bool) {
+	now = now.In(s.location)
+	today := int(now.Month())*100 + now.Day()
+
+	for i, h := range s.holidays {
+		if h.from <= h.to && today >= h.from && today <= h.to ||
+			h.from > h.to && (today >= h.from || today <= h.to) {
+			return &s.holidays[i], true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,95 @@
+package welcomevoice
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduleRuleMatches(t *testing.T) {
+	// 2024-01-01 is a Monday.
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		rule  ScheduleRule
+		guild string
+		now   time.Time
+		want  bool
+	}{
+		{"whole day", ScheduleRule{Action: ScheduleSkip}, "", at(1, 12, 0), true},
+		{"inside", ScheduleRule{From: "09:00", To: "17:00", Action: ScheduleSkip}, "", at(1, 9, 0), true},
+		{"end is excluded", ScheduleRule{From: "09:00", To: "17:00", Action: ScheduleSkip}, "", at(1, 17, 0), false},
+		{"before", ScheduleRule{From: "09:00", To: "17:00", Action: ScheduleSkip}, "", at(1, 8, 59), false},
+		{"night evening", ScheduleRule{From: "23:00", To: "07:00", Action: ScheduleSkip}, "", at(1, 23, 30), true},
+		{"night morning", ScheduleRule{From: "23:00", To: "07:00", Action: ScheduleSkip}, "", at(2, 6, 0), true},
+		{"night day", ScheduleRule{From: "23:00", To: "07:00", Action: ScheduleSkip}, "", at(1, 12, 0), false},
+		{"weekday", ScheduleRule{Days: []string{"weekday"}, Action: ScheduleSkip}, "", at(5, 12, 0), true},
+		{"weekend", ScheduleRule{Days: []string{"weekend"}, Action: ScheduleSkip}, "", at(5, 12, 0), false},
+		// Saturday morning belongs to the Friday night.
+		{"night of friday", ScheduleRule{Days: []string{"fri"}, From: "22:00", To: "06:00", Action: ScheduleSkip}, "", at(6, 5, 0), true},
+		{"night of saturday", ScheduleRule{Days: []string{"sat"}, From: "22:00", To: "06:00", Action: ScheduleSkip}, "", at(6, 5, 0), false},
+		{"monday morning", ScheduleRule{Days: []string{"sun"}, From: "22:00", To: "06:00", Action: ScheduleSkip}, "", at(1, 5, 0), true},
+		{"guild", ScheduleRule{GuildID: "g1", Action: ScheduleSkip}, "g1", at(1, 12, 0), true},
+		{"other guild", ScheduleRule{GuildID: "g1", Action: ScheduleSkip}, "g2", at(1, 12, 0), false},
+		{"timezone", ScheduleRule{Timezone: "Etc/GMT-3", From: "09:00", To: "10:00", Action: ScheduleSkip}, "", at(1, 6, 30), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newScheduleRule(tt.rule, time.UTC)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := r.matches(tt.guild, tt.now); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGreetingVolume(t *testing.T) {
+	s, err := newSchedule(ScheduleConfig{
+		Timezone: "UTC",
+		Rules: []ScheduleRule{
+			{From: "23:00", To: "07:00", Action: ScheduleSkip},
+			{From: "20:00", To: "23:00", Action: ScheduleVolume, Volume: 0.5},
+			{From: "18:00", To: "22:00", Action: ScheduleSkip},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		hour int
+		want float64
+	}{
+		{12, 1},
+		{19, 0},
+		{21, 0.5},
+		{23, 0},
+		{3, 0},
+	} {
+		now := time.Date(2024, 1, 1, tt.hour, 0, 0, 0, time.UTC)
+		if got := s.greetingVolume("", now); got != tt.want {
+			t.Errorf("greetingVolume(%02d:00) = %v, want %v", tt.hour, got, tt.want)
+		}
+	}
+}
+
+func TestNewScheduleRuleInvalid(t *testing.T) {
+	for _, r := range []ScheduleRule{
+		{Action: "loud"},
+		{Action: ScheduleVolume, Volume: 2},
+		{Action: ScheduleSkip, Days: []string{"someday"}},
+		{Action: ScheduleSkip, From: "25:00"},
+	} {
+		if _, err := newScheduleRule(r, time.UTC); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("newScheduleRule(%+v) error = %v, want %v", r, err, ErrInvalidSchedule)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package welcomevoice
+
+import (
+	"bytes"
+	"strings"
+)
+
+type soundFormat struct {
+	Name      string
+	Extension string
+}
+
+var (
+	formatOggOpus = soundFormat{Name: "Ogg/Opus", Extension: ".ogg"}
+	formatOgg     = soundFormat{Name: "Ogg", Extension: ".ogg"}
+	formatMP3     = soundFormat{Name: "MP3", Extension: ".mp3"}
+	formatWAV     = soundFormat{Name: "WAV", Extension: ".wav"}
+	formatFLAC    = soundFormat{Name: "FLAC", Extension: ".flac"}
+	formatAAC     = soundFormat{Name: "AAC", Extension: ".aac"}
+	formatM4A     = soundFormat{Name: "M4A", Extension: ".m4a"}
+	formatWebM    = soundFormat{Name: "WebM", Extension: ".webm"}
+	formatMP4     = soundFormat{Name: "MP4", Extension: ".mp4"}
+)
+
+// supportedFormats lists the formats accepted from users.
+var supportedFormats = []soundFormat{
+	formatOggOpus, formatMP3, formatWAV, formatFLAC, formatAAC, formatM4A, formatWebM, formatMP4,
+}
+
+// sniffFormat detects the container by its magic bytes. Content-Type headers
+// of the Discord CDN are often generic and can not be trusted.
+func sniffFormat(head []byte) (soundFormat, bool) {
+	switch {
+	case bytes.HasPrefix(head, []byte("OggS")):
+		// The first page of an Ogg Opus stream carries the identification
+		// header right after the 27 byte page header and the segment table.
+		if len(head) > 27 && len(head) >= 27+int(head[26]) {
+			if bytes.HasPrefix(head[27+int(head[26]):], []byte("OpusHead")) {
+				return formatOggOpus, true
+			}
+		}
+
+		return formatOgg, true
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return formatMP3, true
+	case len(head) >= 12 && bytes.Equal(head[0:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WAVE")):
+		return formatWAV, true
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return formatFLAC, true
+	case bytes.HasPrefix(head, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return formatWebM, true
+	case len(head) >= 12 && bytes.Equal(head[4:8], []byte("ftyp")):
+		// Images such as HEIC and AVIF use the same container, only audio
+		// and video brands are accepted.
+		switch string(head[8:12]) {
+		case "M4A ", "M4B ", "M4P ", "F4A ":
+			return formatM4A, true
+		case "isom", "iso2", "iso4", "iso5", "iso6", "mp41", "mp42", "avc1",
+			"dash", "M4V ", "F4V ", "qt  ", "3gp4", "3gp5", "3gp6", "3g2a", "MSNV":
+			return formatMP4, true
+		default:
+			return soundFormat{}, false
+		}
+	case len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+		// MPEG audio frame sync. Layer bits are zero for ADTS AAC.
+		switch head[1] & 0x06 {
+		case 0x00:
+			return formatAAC, true
+		default:
+			return formatMP3, true
+		}
+	}
+
+	return soundFormat{}, false
+}
+
+func supportedFormatNames() string {
+	names := make([]string, 0, len(supportedFormats))
+	for _, f := range supportedFormats {
+		names = append(names, f.Name)
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,52 @@
+package welcomevoice
+
+import (
+	"testing"
+)
+
+func oggPage(payload string) []byte {
+	page := append([]byte("OggS"), make([]byte, 22)...)
+	page = append(page, 1, byte(len(payload)))
+
+	return append(page, payload...)
+}
+
+func ftyp(brand string) []byte {
+	return append([]byte{0, 0, 0, 0x20}, "ftyp"+brand...)
+}
+
+func TestSniffFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		head   []byte
+		want   soundFormat
+		wantOK bool
+	}{
+		{name: "ogg opus", head: oggPage("OpusHead"), want: formatOggOpus, wantOK: true},
+		{name: "ogg vorbis", head: oggPage("\x01vorbis"), want: formatOgg, wantOK: true},
+		{name: "short ogg", head: []byte("OggS"), want: formatOgg, wantOK: true},
+		{name: "mp3 id3", head: []byte("ID3\x04"), want: formatMP3, wantOK: true},
+		{name: "mp3 frame", head: []byte{0xFF, 0xFB, 0x90}, want: formatMP3, wantOK: true},
+		{name: "aac adts", head: []byte{0xFF, 0xF1, 0x50}, want: formatAAC, wantOK: true},
+		{name: "wav", head: []byte("RIFF\x00\x00\x00\x00WAVEfmt "), want: formatWAV, wantOK: true},
+		{name: "avi", head: []byte("RIFF\x00\x00\x00\x00AVI LIST")},
+		{name: "flac", head: []byte("fLaC"), want: formatFLAC, wantOK: true},
+		{name: "webm", head: []byte{0x1A, 0x45, 0xDF, 0xA3}, want: formatWebM, wantOK: true},
+		{name: "m4a", head: ftyp("M4A "), want: formatM4A, wantOK: true},
+		{name: "mp4", head: ftyp("isom"), want: formatMP4, wantOK: true},
+		{name: "dash", head: ftyp("dash"), want: formatMP4, wantOK: true},
+		{name: "heic", head: ftyp("heic")},
+		{name: "avif", head: ftyp("avif")},
+		{name: "png", head: []byte("\x89PNG\r\n\x1a\n")},
+		{name: "empty", head: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := sniffFormat(tt.head)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("sniffFormat() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
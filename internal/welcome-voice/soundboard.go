@@ -0,0 +1,164 @@
+package welcomevoice
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/tekig/mog-go/internal/duration"
+	"golang.org/x/exp/slices"
+)
+
+type SoundboardConfig struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// RoleIDs limits the soundboard to members with one of the roles,
+	// everyone may use it when empty.
+	RoleIDs  []string          `json:"role_ids,omitempty"`
+	Cooldown duration.Duration `json:"cooldown,omitempty"`
+}
+
+func (w *WelcomeVoice) canUseSoundboard(member *discordgo.Member) bool {
+	roles := w.config.Soundboard.RoleIDs
+	if len(roles) == 0 || w.isModerator(member) {
+		return true
+	}
+	if member == nil {
+		return false
+	}
+
+	return slices.IndexFunc(member.Roles, func(role string) bool {
+		return slices.Contains(roles, role)
+	}) != -1
+}
+
+// voiceChannel returns the voice channel the user is in.
+func (w *WelcomeVoice) voiceChannel(guildID, userID string) (string, bool) {
+	if channelID, ok := w.channelByUser[userID]; ok {
+		return channelID, true
+	}
+
+	// Users who joined before the bot started are only known to the state.
+	state, err := w.client.State.VoiceState(guildID, userID)
+	if err != nil || state.ChannelID == "" {
+		return "", false
+	}
+
+	return state.ChannelID, true
+}
+
+// startSoundboard checks the caller may play a sound now and returns their
+// voice channel. Must be called with w.mu held.
+func (w *WelcomeVoice) startSoundboard(c *commandContext) (string, error) {
+	if !w.canUseSoundboard(c.Member) {
+		return "", reject("You are not allowed to use the soundboard.")
+	}
+
+	channelID, ok := w.voiceChannel(c.GuildID, c.Author.ID)
+	if !ok {
+		return "", reject("Join a voice channel first.")
+	}
+
+	if last, ok := w.soundboardUsed[c.Author.ID]; ok {
+		if wait := w.config.Soundboard.Cooldown.Duration - time.Since(last); wait > 0 {
+			return "", reject("Wait %s before playing another sound.", wait.Round(time.Second))
+		}
+	}
+	w.soundboardUsed[c.Author.ID] = time.Now()
+
+	return channelID, nil
+}
+
+func (w *WelcomeVoice) commandPlay(c *commandContext, args []string) (*discordgo.MessageSend, error) {
+	userID := c.Author.ID
+	if len(args) > 0 {
+		id, err := parseUser(args[0])
+		if err != nil {
+			return nil, err
+		}
+		userID = id
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sound := w.pathSoundData(userID)
+	if _, err := os.Stat(sound); errors.Is(err, os.ErrNotExist) {
+		sound = w.pathFallbackData(userID)
+		if _, err := os.Stat(sound); errors.Is(err, os.ErrNotExist) {
+			return nil, reject("<@%s> has no sound.", userID)
+		}
+	}
+
+	channelID, err := w.startSoundboard(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// The reply can not wait for the playback, slash commands must be
+	// answered within seconds.
+	go func() {
+		p, err := w.play(sound, c.GuildID, channelID)
+
+		w.mu.Lock()
+		w.recordPlayback(playKindSoundboard, userID, sound, c.GuildID, channelID, p, err)
+		w.mu.Unlock()
+
+		if err != nil {
+			w.logger.Printf("soundboard: play %s: %s", userID, err.Error())
+		}
+	}()
+
+	return textReply("Playing the sound of <@%s>.", userID), nil
+}
+
+func (w *WelcomeVoice) commandRandom(c *commandContext, _ []string) (*discordgo.MessageSend, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	channelID, err := w.startSoundboard(c)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		if err := w.playRandom(c.GuildID, channelID); err != nil {
+			w.logger.Printf("soundboard: random: %s", err.Error())
+		}
+	}()
+
+	return textReply("Playing a random sound."), nil
+}
+
+// playRandom fetches and converts a random sound within the conversion
+// timeout and plays it. Only the playback is recorded under the lock.
+func (w *WelcomeVoice) playRandom(guildID, channelID string) error {
+	ctx, cancel := w.withConvertTimeout(w.ctx)
+	defer cancel()
+
+	sound, err := w.random.RandomSound(ctx)
+	if err != nil {
+		return fmt.Errorf("random sound: %w", err)
+	}
+	defer sound.Close()
+
+	f, err := os.CreateTemp("", "mog-*"+voiceExtension)
+	if err != nil {
+		return fmt.Errorf("create temp: %w", err)
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+
+	if err := w.convertSound(ctx, sound.Path, f.Name(), trimRange{}); err != nil {
+		return fmt.Errorf("convert sound: %w", err)
+	}
+
+	p, err := w.play(f.Name(), guildID, channelID)
+
+	w.mu.Lock()
+	w.recordPlayback(playKindRandom, "", f.Name(), guildID, channelID, p, err)
+	w.mu.Unlock()
+
+	return err
+}
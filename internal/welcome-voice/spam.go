@@ -0,0 +1,164 @@
+package welcomevoice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/tekig/mog-go/internal/duration"
+)
+
+const (
+	spamPenalized       = "penalized"
+	spamBurst           = "burst"
+	spamUserCooldown    = "user cooldown"
+	spamChannelCooldown = "channel cooldown"
+
+	// penaltyForget is how long after the last penalty the escalation
+	// starts over.
+	penaltyForget = 24 * time.Hour
+)
+
+type CooldownConfig struct {
+	// User is the minimum time between greetings of one user.
+	User duration.Duration `json:"user,omitempty"`
+	// Channel is the minimum time between greetings in one channel.
+	Channel duration.Duration `json:"channel,omitempty"`
+	// BurstLimit joins of one user within BurstWindow are allowed, the next
+	// one disables the user's greeting for Penalty.
+	BurstLimit  int               `json:"burst_limit,omitempty"`
+	BurstWindow duration.Duration `json:"burst_window,omitempty"`
+	// Penalty doubles on every repeated burst up to MaxPenalty.
+	Penalty    duration.Duration `json:"penalty,omitempty"`
+	MaxPenalty duration.Duration `json:"max_penalty,omitempty"`
+}
+
+type penalty struct {
+	Until time.Time
+	Level int
+}
+
+// spamGuard remembers recent greetings to throttle reconnecting users.
+type spamGuard struct {
+	joins       map[string][]time.Time
+	lastUser    map[string]time.Time
+	lastChannel map[string]time.Time
+	penalties   map[string]*penalty
+	events      map[string]int
+}
+
+func newSpamGuard() *spamGuard {
+	return &spamGuard{
+		joins:       make(map[string][]time.Time),
+		lastUser:    make(map[string]time.Time),
+		lastChannel: make(map[string]time.Time),
+		penalties:   make(map[string]*penalty),
+		events:      make(map[string]int),
+	}
+}
+
+// allowGreeting counts the join and reports whether the greeting may be
+// played. Must be called with w.mu held.
+func (w *WelcomeVoice) allowGreeting(userID, channelID string) bool {
+	c := w.config.Cooldown
+	g := w.spam
+	now := time.Now()
+
+	if p, ok := g.penalties[userID]; ok && now.Before(p.Until) {
+		w.spamEvent(spamPenalized, userID, channelID)
+		return false
+	}
+
+	if c.BurstLimit > 0 {
+		joins := g.joins[userID][:0]
+		for _, t := range g.joins[userID] {
+			if now.Sub(t) < c.BurstWindow.Duration {
+				joins = append(joins, t)
+			}
+		}
+		joins = append(joins, now)
+		g.joins[userID] = joins
+
+		if len(joins) > c.BurstLimit {
+			w.penalize(userID, now)
+			w.spamEvent(spamBurst, userID, channelID)
+			return false
+		}
+	}
+
+	if last, ok := g.lastUser[userID]; ok && now.Sub(last) < c.User.Duration {
+		w.spamEvent(spamUserCooldown, userID, channelID)
+		return false
+	}
+
+	if last, ok := g.lastChannel[channelID]; ok && now.Sub(last) < c.Channel.Duration {
+		w.spamEvent(spamChannelCooldown, userID, channelID)
+		return false
+	}
+
+	return true
+}
+
+// greeted starts the cooldowns after the greeting was played.
+func (w *WelcomeVoice) greeted(userID, channelID string) {
+	now := time.Now()
+	w.spam.lastUser[userID] = now
+	w.spam.lastChannel[channelID] = now
+}
+
+func (w *WelcomeVoice) penalize(userID string, now time.Time) {
+	c := w.config.Cooldown
+
+	p, ok := w.spam.penalties[userID]
+	if !ok || now.Sub(p.Until) > penaltyForget {
+		p = &penalty{}
+		w.spam.penalties[userID] = p
+	}
+	p.Level++
+
+	d := c.Penalty.Duration << (p.Level - 1)
+	if max := c.MaxPenalty.Duration; max > 0 && (d > max || d <= 0) {
+		d = max
+	}
+	p.Until = now.Add(d)
+	delete(w.spam.joins, userID)
+
+	w.logger.Printf("spam: greeting of %s disabled for %s (level %d)", userID, d, p.Level)
+}
+
+func (w *WelcomeVoice) spamEvent(event, userID, channelID string) {
+	w.spam.events[event]++
+	w.logger.Printf("spam: %s: user %s, channel %s (%d total)", event, userID, channelID, w.spam.events[event])
+}
+
+func (w *WelcomeVoice) commandSpam(_ *commandContext, _ []string) (*discordgo.MessageSend, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var b strings.Builder
+
+	b.WriteString("Skipped greetings:")
+	for _, event := range []string{spamPenalized, spamBurst, spamUserCooldown, spamChannelCooldown} {
+		fmt.Fprintf(&b, "\n%s: %d", event, w.spam.events[event])
+	}
+
+	userIDs := make([]string, 0, len(w.spam.penalties))
+	for userID, p := range w.spam.penalties {
+		if time.Now().Before(p.Until) {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	sort.Strings(userIDs)
+
+	if len(userIDs) > 0 {
+		b.WriteString("\nDisabled greetings:")
+	}
+	for _, userID := range userIDs {
+		p := w.spam.penalties[userID]
+		fmt.Fprintf(&b, "\n<@%s> until <t:%d:t> (level %d)", userID, p.Until.Unix(), p.Level)
+	}
+
+	return textReply("%s", b.String()), nil
+}
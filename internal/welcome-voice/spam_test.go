@@ -0,0 +1,63 @@
+package welcomevoice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tekig/mog-go/internal/duration"
+)
+
+func TestAllowGreetingPenaltyDoubles(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{
+		Cooldown: CooldownConfig{
+			BurstLimit:  2,
+			BurstWindow: duration.Duration{Duration: time.Minute},
+			Penalty:     duration.Duration{Duration: time.Minute},
+			MaxPenalty:  duration.Duration{Duration: 3 * time.Minute},
+		},
+	})
+	w.spam = newSpamGuard()
+
+	for _, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		if !w.allowGreeting("1", "c") || !w.allowGreeting("1", "c") {
+			t.Fatal("allowGreeting() = false within the burst limit")
+		}
+
+		before := time.Now()
+		if w.allowGreeting("1", "c") {
+			t.Fatal("allowGreeting() = true over the burst limit")
+		}
+		if w.allowGreeting("1", "c") {
+			t.Fatal("allowGreeting() = true while penalized")
+		}
+
+		p := w.spam.penalties["1"]
+		if got := p.Until.Sub(before); got < want || got > want+time.Second {
+			t.Errorf("level %d: penalty = %s, want %s", p.Level, got, want)
+		}
+
+		// Let the penalty expire.
+		p.Until = time.Now().Add(-time.Second)
+	}
+}
+
+func TestAllowGreetingPenaltyForgotten(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{
+		Cooldown: CooldownConfig{
+			BurstLimit:  1,
+			BurstWindow: duration.Duration{Duration: time.Minute},
+			Penalty:     duration.Duration{Duration: time.Minute},
+		},
+	})
+	w.spam = newSpamGuard()
+	w.spam.penalties["1"] = &penalty{Until: time.Now().Add(-penaltyForget - time.Hour), Level: 5}
+
+	w.allowGreeting("1", "c")
+	if w.allowGreeting("1", "c") {
+		t.Fatal("allowGreeting() = true over the burst limit")
+	}
+
+	if p := w.spam.penalties["1"]; p.Level != 1 {
+		t.Errorf("level = %d, want 1", p.Level)
+	}
+}
@@ -0,0 +1,197 @@
+package welcomevoice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	playKindGreeting   = "greeting"
+	playKindUpload     = "upload"
+	playKindSoundboard = "soundboard"
+	playKindRandom     = "random"
+
+	// playbackLogSize is the number of playbacks kept in detail, the totals
+	// are kept forever.
+	playbackLogSize = 500
+	leaderboardSize = 5
+)
+
+// playback is what happened while a sound was played.
+type playback struct {
+	Played time.Duration
+	// CutShort is set if voice_duration stopped the sound before its end.
+	CutShort bool
+}
+
+type playbackRecord struct {
+	Kind string `json:"kind"`
+	// UserID is whose sound was played, empty for random sounds.
+	UserID    string        `json:"user_id,omitempty"`
+	GuildID   string        `json:"guild_id"`
+	ChannelID string        `json:"channel_id"`
+	Sound     string        `json:"sound,omitempty"`
+	Played    time.Duration `json:"played"`
+	CutShort  bool          `json:"cut_short,omitempty"`
+	Error     string        `json:"error,omitempty"`
+	At        time.Time     `json:"at"`
+}
+
+type playStats struct {
+	Plays    int           `json:"plays"`
+	Failures int           `json:"failures,omitempty"`
+	CutShort int           `json:"cut_short,omitempty"`
+	Played   time.Duration `json:"played"`
+	// UserID is the last user who had the sound.
+	UserID string `json:"user_id,omitempty"`
+}
+
+func (s *playStats) add(r *playbackRecord) {
+	if r.Error != "" {
+		s.Failures++
+		return
+	}
+
+	s.Plays++
+	s.Played += r.Played
+	if r.CutShort {
+		s.CutShort++
+	}
+}
+
+// recordPlayback keeps the result of playing the sound file for the
+// statistics.
+func (w *WelcomeVoice) recordPlayback(kind, userID, sound, guildID, channelID string, p *playback, err error) {
+	r := &playbackRecord{
+		Kind:      kind,
+		UserID:    userID,
+		GuildID:   guildID,
+		ChannelID: channelID,
+		At:        time.Now(),
+	}
+	// Random sounds are temporary files, their content is not tracked.
+	if kind != playKindRandom {
+		if hash, err := hashFile(sound); err == nil {
+			r.Sound = hash
+		}
+	}
+	if p != nil {
+		r.Played = p.Played
+		r.CutShort = p.CutShort
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	w.repository.Playbacks = append(w.repository.Playbacks, r)
+	if over := len(w.repository.Playbacks) - playbackLogSize; over > 0 {
+		w.repository.Playbacks = w.repository.Playbacks[over:]
+	}
+
+	if userID != "" {
+		stats, ok := w.repository.UserStats[userID]
+		if !ok {
+			stats = &playStats{}
+			w.repository.UserStats[userID] = stats
+		}
+		stats.add(r)
+	}
+
+	if r.Sound != "" {
+		stats, ok := w.repository.SoundStats[r.Sound]
+		if !ok {
+			stats = &playStats{}
+			w.repository.SoundStats[r.Sound] = stats
+		}
+		stats.add(r)
+		if userID != "" {
+			stats.UserID = userID
+		}
+	}
+
+	w.saveRepository()
+}
+
+func (w *WelcomeVoice) commandStats(c *commandContext, args []string) (*discordgo.MessageSend, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(args) > 0 {
+		userID, err := parseUser(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return textReply("%s", w.userStats(userID)), nil
+	}
+
+	var b strings.Builder
+
+	b.WriteString("**Most greeted users**\n")
+	for i, userID := range topStats(w.repository.UserStats) {
+		s := w.repository.UserStats[userID]
+		fmt.Fprintf(&b, "%d. <@%s> — %d plays, %s\n", i+1, userID, s.Plays, s.Played.Round(time.Second))
+	}
+
+	b.WriteString("\n**Most played sounds**\n")
+	for i, hash := range topStats(w.repository.SoundStats) {
+		s := w.repository.SoundStats[hash]
+		// Random sounds are not tracked, every sound has a user.
+		fmt.Fprintf(&b, "%d. `%s` sound of <@%s> — %d plays\n", i+1, hash[:8], s.UserID, s.Plays)
+	}
+
+	b.WriteString("\n")
+	b.WriteString(w.userStats(c.Author.ID))
+
+	return textReply("%s", b.String()), nil
+}
+
+func (w *WelcomeVoice) userStats(userID string) string {
+	s, ok := w.repository.UserStats[userID]
+	if !ok {
+		return fmt.Sprintf("The sound of <@%s> was not played yet.", userID)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Stats of <@%s>**\n", userID)
+	fmt.Fprintf(&b, "Played: %d times, %s in total\n", s.Plays, s.Played.Round(time.Second))
+	fmt.Fprintf(&b, "Cut short: %d times\n", s.CutShort)
+	fmt.Fprintf(&b, "Failed: %d times", s.Failures)
+
+	for i := len(w.repository.Playbacks) - 1; i >= 0; i-- {
+		r := w.repository.Playbacks[i]
+		if r.UserID == userID && r.Error != "" {
+			fmt.Fprintf(&b, ", last <t:%d:R>: %s", r.At.Unix(), r.Error)
+			break
+		}
+	}
+
+	return b.String()
+}
+
+// topStats returns the keys with the most plays, ties are broken by the
+// key to keep the order stable.
+func topStats(stats map[string]*playStats) []string {
+	keys := make([]string, 0, len(stats))
+	for k, s := range stats {
+		if s.Plays > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if a, b := stats[keys[i]].Plays, stats[keys[j]].Plays; a != b {
+			return a > b
+		}
+		return keys[i] < keys[j]
+	})
+
+	if len(keys) > leaderboardSize {
+		keys = keys[:leaderboardSize]
+	}
+
+	return keys
+}
@@ -0,0 +1,24 @@
+package welcomevoice
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordPlaybackSkipsRandomSounds(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{})
+	sound := writeOpus(t, 312, []uint64{960}, nil)
+
+	w.recordPlayback(playKindRandom, "", sound, "g", "c", &playback{Played: time.Second}, nil)
+	if len(w.repository.SoundStats) != 0 {
+		t.Errorf("sound stats = %v, want none for random sounds", w.repository.SoundStats)
+	}
+
+	w.recordPlayback(playKindGreeting, "1", sound, "g", "c", &playback{Played: time.Second}, nil)
+	if len(w.repository.SoundStats) != 1 {
+		t.Errorf("got %d sound stats, want 1", len(w.repository.SoundStats))
+	}
+	if got := w.repository.UserStats["1"].Plays; got != 1 {
+		t.Errorf("user plays = %d, want 1", got)
+	}
+}
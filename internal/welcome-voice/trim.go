@@ -0,0 +1,140 @@
+package welcomevoice
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	trimRangeRegexp = regexp.MustCompile(`(?:^|\s)(\d+(?::\d+){0,2}(?:\.\d+)?)\s*-\s*(\d+(?::\d+){0,2}(?:\.\d+)?)(?:\s|$)`)
+	trimKeyRegexp   = regexp.MustCompile(`(?i)\b(start|len|end)=(\S+)`)
+)
+
+// trimRange is a part of the uploaded clip chosen by the user. Zero Length
+// means up to the end of the clip.
+type trimRange struct {
+	Start  time.Duration `json:"start,omitempty"`
+	Length time.Duration `json:"length,omitempty"`
+}
+
+func (t trimRange) IsZero() bool {
+	return t.Start == 0 && t.Length == 0
+}
+
+func (t trimRange) String() string {
+	if t.Length == 0 {
+		return formatTimestamp(t.Start) + "-end"
+	}
+
+	return formatTimestamp(t.Start) + "-" + formatTimestamp(t.Start+t.Length)
+}
+
+// parseTrimRange finds a time range in the message content. Both
+// "0:03-0:07" and "start=3.5 len=2" (or "end=5.5") forms are accepted.
+func parseTrimRange(content string) (trimRange, error) {
+	// Links carry their own "start=" query parameters.
+	content = urlRegexp.ReplaceAllString(content, " ")
+
+	if m := trimRangeRegexp.FindStringSubmatch(content); m != nil {
+		start, err := parseTimestamp(m[1])
+		if err != nil {
+			return trimRange{}, err
+		}
+		end, err := parseTimestamp(m[2])
+		if err != nil {
+			return trimRange{}, err
+		}
+		if end <= start {
+			return trimRange{}, reject("Trim range %s-%s ends before it starts.", m[1], m[2])
+		}
+
+		return trimRange{Start: start, Length: end - start}, nil
+	}
+
+	var (
+		t   trimRange
+		end time.Duration
+	)
+	for _, m := range trimKeyRegexp.FindAllStringSubmatch(content, -1) {
+		v, err := parseTimestamp(m[2])
+		if err != nil {
+			return trimRange{}, err
+		}
+
+		switch strings.ToLower(m[1]) {
+		case "start":
+			t.Start = v
+		case "len":
+			t.Length = v
+		case "end":
+			end = v
+		}
+	}
+
+	if end > 0 {
+		if t.Length > 0 {
+			return trimRange{}, reject("Use either `len` or `end`, not both.")
+		}
+		if end <= t.Start {
+			return trimRange{}, reject("Trim range ends before it starts.")
+		}
+		t.Length = end - t.Start
+	}
+
+	return t, nil
+}
+
+// parseTimestamp parses seconds with an optional fraction, optionally
+// prefixed with minutes and hours: "3.5", "0:03", "1:02:03.25".
+func parseTimestamp(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, reject("Invalid timestamp `%s`.", s)
+	}
+
+	var seconds float64
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || v < 0 || (i > 0 && v >= 60) {
+			return 0, reject("Invalid timestamp `%s`.", s)
+		}
+		if i < len(parts)-1 && v != float64(int(v)) {
+			return 0, reject("Invalid timestamp `%s`.", s)
+		}
+
+		seconds = seconds*60 + v
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+func formatTimestamp(d time.Duration) string {
+	m := int(d / time.Minute)
+	s := (d % time.Minute).Seconds()
+
+	return fmt.Sprintf("%d:%05.2f", m, s)
+}
+
+// validateTrim checks the range against the clip length and the maximum
+// voice duration.
+func (w *WelcomeVoice) validateTrim(t trimRange, clip time.Duration) error {
+	if t.Start >= clip {
+		return reject("Trim range %s starts after the end of the clip (%s).", t, formatTimestamp(clip))
+	}
+	if t.Length > 0 && t.Start+t.Length > clip {
+		return reject("Trim range %s is outside the clip (%s).", t, formatTimestamp(clip))
+	}
+	if max := w.config.VoiceDuration.Duration; max > 0 {
+		if t.Length > max {
+			return reject("Trim range %s is longer than the maximum of %s.", t, max)
+		}
+		if t.Length == 0 && clip-t.Start > max {
+			return reject("Trim range %s is longer than the maximum of %s, add `len` or `end`.", t, max)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package welcomevoice
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tekig/mog-go/internal/duration"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "3", want: 3 * time.Second},
+		{in: "3.5", want: 3500 * time.Millisecond},
+		{in: "0:03", want: 3 * time.Second},
+		{in: "1:02:03.25", want: time.Hour + 2*time.Minute + 3250*time.Millisecond},
+		{in: "0:60", wantErr: true},
+		{in: "1.5:00", wantErr: true},
+		{in: "1:2:3:4", wantErr: true},
+		{in: "abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTimestamp(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTimestamp(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseTimestamp(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTrimRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    trimRange
+		wantErr bool
+	}{
+		{in: "", want: trimRange{}},
+		{in: "my sound", want: trimRange{}},
+		{in: "0:03-0:07", want: trimRange{Start: 3 * time.Second, Length: 4 * time.Second}},
+		{in: "cut 1.5 - 2.5 please", want: trimRange{Start: 1500 * time.Millisecond, Length: time.Second}},
+		{in: "start=3.5 len=2", want: trimRange{Start: 3500 * time.Millisecond, Length: 2 * time.Second}},
+		{in: "START=1 end=4", want: trimRange{Start: time.Second, Length: 3 * time.Second}},
+		{in: "start=2", want: trimRange{Start: 2 * time.Second}},
+		{in: "https://youtu.be/x?start=30", want: trimRange{}},
+		{in: "https://example.com/a.ogg?start=30 len=2", want: trimRange{Length: 2 * time.Second}},
+		{in: "0:07-0:03", wantErr: true},
+		{in: "len=1 end=2", wantErr: true},
+		{in: "start=5 end=4", wantErr: true},
+		{in: "start=x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTrimRange(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseTrimRange(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		var rejectErr *RejectError
+		if err != nil && !errors.As(err, &rejectErr) {
+			t.Errorf("parseTrimRange(%q) error = %v, want a reject error", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("parseTrimRange(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTrim(t *testing.T) {
+	w := &WelcomeVoice{config: Config{VoiceDuration: duration.Duration{Duration: 5 * time.Second}}}
+
+	tests := []struct {
+		name    string
+		trim    trimRange
+		clip    time.Duration
+		wantErr bool
+	}{
+		{name: "inside", trim: trimRange{Start: time.Second, Length: 2 * time.Second}, clip: 10 * time.Second},
+		{name: "start after end", trim: trimRange{Start: 10 * time.Second}, clip: 10 * time.Second, wantErr: true},
+		{name: "outside", trim: trimRange{Start: 8 * time.Second, Length: 3 * time.Second}, clip: 10 * time.Second, wantErr: true},
+		{name: "too long", trim: trimRange{Length: 6 * time.Second}, clip: 10 * time.Second, wantErr: true},
+		{name: "start only fits", trim: trimRange{Start: 6 * time.Second}, clip: 10 * time.Second},
+		{name: "start only too long", trim: trimRange{Start: time.Second}, clip: 10 * time.Second, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := w.validateTrim(tt.trim, tt.clip); (err != nil) != tt.wantErr {
+				t.Errorf("validateTrim() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package welcomevoice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	TTSEspeak = "espeak-ng"
+	TTSPiper  = "piper"
+
+	ttsDir = "tts"
+
+	defaultTTSTemplate = "Welcome, {name}"
+)
+
+type TTSConfig struct {
+	// Engine is "espeak-ng" or "piper", spoken greetings are off if empty.
+	Engine string `json:"engine,omitempty"`
+	// Binary overrides the path of the engine executable.
+	Binary string `json:"binary,omitempty"`
+	// Voice is the espeak-ng voice, e.g. "en-us".
+	Voice string `json:"voice,omitempty"`
+	// Model is the piper voice model file.
+	Model string `json:"model,omitempty"`
+	// Template is the spoken text, {name} is replaced with the display name.
+	Template string `json:"template,omitempty"`
+}
+
+// Synthesizer turns text into speech.
+type Synthesizer interface {
+	// Synthesize writes the speech to a WAV file.
+	Synthesize(ctx context.Context, text, to string) error
+}
+
+func NewSynthesizer(config TTSConfig) (Synthesizer, error) {
+	switch config.Engine {
+	case "":
+		return nil, nil
+	case TTSEspeak:
+		binary := config.Binary
+		if binary == "" {
+			binary = TTSEspeak
+		}
+
+		return &EspeakSynthesizer{Binary: binary, Voice: config.Voice}, nil
+	case TTSPiper:
+		binary := config.Binary
+		if binary == "" {
+			binary = TTSPiper
+		}
+		if config.Model == "" {
+			return nil, fmt.Errorf("piper: %w", ErrNoTTSModel)
+		}
+
+		return &PiperSynthesizer{Binary: binary, Model: config.Model}, nil
+	default:
+		return nil, fmt.Errorf("engine %q: %w", config.Engine, ErrUnknownTTS)
+	}
+}
+
+type EspeakSynthesizer struct {
+	Binary string
+	Voice  string
+}
+
+func (s *EspeakSynthesizer) Synthesize(ctx context.Context, text, to string) error {
+	args := []string{"-w", to}
+	if s.Voice != "" {
+		args = append(args, "-v", s.Voice)
+	}
+	// Text after "--" is never read as an option, names may start with "-".
+	args = append(args, "--", text)
+
+	output, err := exec.CommandContext(ctx, s.Binary, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s, %w", s.Binary, string(output), err)
+	}
+
+	return nil
+}
+
+type PiperSynthesizer struct {
+	Binary string
+	Model  string
+}
+
+func (s *PiperSynthesizer) Synthesize(ctx context.Context, text, to string) error {
+	cmd := exec.CommandContext(ctx, s.Binary, "--model", s.Model, "--output_file", to)
+	cmd.Stdin = strings.NewReader(text)
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %s, %w", s.Binary, string(output), err)
+	}
+
+	return nil
+}
+
+// displayName returns the name the member is shown with in the guild.
+func displayName(member *discordgo.Member) string {
+	if member == nil {
+		return ""
+	}
+	if member.Nick != "" {
+		return member.Nick
+	}
+	if member.User != nil {
+		return member.User.Username
+	}
+
+	return ""
+}
+
+// speechSound returns the spoken greeting for the name, synthesizing it on
+// the first use within the conversion timeout. Greetings are cached by their
+// text. It runs without the lock.
+func (w *WelcomeVoice) speechSound(ctx context.Context, name string) (string, error) {
+	template := w.config.TTS.Template
+	if template == "" {
+		template = defaultTTSTemplate
+	}
+	text := strings.ReplaceAll(template, "{name}", name)
+
+	sum := sha256.Sum256([]byte(w.config.TTS.Engine + "\x00" + w.config.TTS.Voice + w.config.TTS.Model + "\x00" + text))
+	cached := path.Join(w.config.VoiceDir, ttsDir, hex.EncodeToString(sum[:16])+voiceExtension)
+
+	if _, err := os.Stat(cached); err == nil {
+		return cached, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("stat cache: %w", err)
+	}
+
+	ctx, cancel := w.withConvertTimeout(ctx)
+	defer cancel()
+
+	f, err := os.CreateTemp("", "mog-*.wav")
+	if err != nil {
+		return "", fmt.Errorf("create temp: %w", err)
+	}
+	_ = f.Close()
+	defer os.Remove(f.Name())
+
+	if err := w.synthesizer.Synthesize(ctx, text, f.Name()); err != nil {
+		return "", fmt.Errorf("synthesize: %w", err)
+	}
+
+	if err := w.convertSound(ctx, f.Name(), cached, trimRange{}); err != nil {
+		return "", fmt.Errorf("convert sound: %w", err)
+	}
+
+	return cached, nil
+}
@@ -0,0 +1,75 @@
+package welcomevoice
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/tekig/mog-go/internal/duration"
+)
+
+// testSynthesizer writes a prepared Ogg Opus file instead of speech and
+// records how it was called.
+type testSynthesizer struct {
+	w        *WelcomeVoice
+	speech   []byte
+	texts    []string
+	unlocked bool
+	deadline bool
+}
+
+func (s *testSynthesizer) Synthesize(ctx context.Context, text, to string) error {
+	if s.w.mu.TryLock() {
+		s.unlocked = true
+		s.w.mu.Unlock()
+	}
+	_, s.deadline = ctx.Deadline()
+	s.texts = append(s.texts, text)
+
+	return os.WriteFile(to, s.speech, 0644)
+}
+
+func TestUserSoundSpeech(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{
+		TTS: TTSConfig{Template: "Hello, {name}"},
+		Convert: ConvertConfig{
+			DisableNormalize:   true,
+			DisableTrimSilence: true,
+			Timeout:            duration.Duration{Duration: time.Minute},
+		},
+	})
+	if err := os.MkdirAll(path.Join(w.config.VoiceDir, ttsDir), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	speech, err := os.ReadFile(writeOpus(t, 312, []uint64{960, 1920}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	synthesizer := &testSynthesizer{w: w, speech: speech}
+	w.synthesizer = synthesizer
+
+	member := &discordgo.Member{Nick: "Alice"}
+	for i := 0; i < 2; i++ {
+		got, err := w.userSound(context.Background(), "1", "g", member)
+		if err != nil {
+			t.Fatalf("userSound() error = %v", err)
+		}
+		if err := checkSound(got); err != nil {
+			t.Errorf("speech is not playable: %v", err)
+		}
+	}
+
+	if len(synthesizer.texts) != 1 || synthesizer.texts[0] != "Hello, Alice" {
+		t.Errorf("synthesized %q, want Hello, Alice once", synthesizer.texts)
+	}
+	if !synthesizer.unlocked {
+		t.Error("the speech was synthesized under the lock")
+	}
+	if !synthesizer.deadline {
+		t.Error("the speech was synthesized without the conversion timeout")
+	}
+}
@@ -0,0 +1,202 @@
+package welcomevoice
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// queuedEmoji marks messages whose sound waits for or is in conversion.
+	queuedEmoji = "⏳"
+	// stagingDir holds converted sounds until they are put in use.
+	stagingDir = "staging"
+)
+
+// uploadQueue runs sound conversions on a fixed number of workers, so
+// downloads and ffmpeg never block the Discord handlers.
+type uploadQueue struct {
+	workers int
+	timeout time.Duration
+	jobs    chan *uploadJob
+
+	mu sync.Mutex
+	// byUser is the latest upload of every user, older ones are cancelled.
+	byUser map[string]*uploadJob
+}
+
+type uploadJob struct {
+	m          *discordgo.Message
+	superseded bool
+	started    bool
+	// notice is the reply telling the queue position, removed on start.
+	notice string
+	cancel context.CancelFunc
+}
+
+func newUploadQueue(config ConvertConfig) *uploadQueue {
+	workers := config.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return &uploadQueue{
+		workers: workers,
+		timeout: config.Timeout.Duration,
+		jobs:    make(chan *uploadJob, config.QueueSize),
+		byUser:  make(map[string]*uploadJob),
+	}
+}
+
+// enqueue adds the message to the queue, cancelling the user's previous
+// upload if it is not done yet. It returns the position in the queue.
+func (q *uploadQueue) enqueue(m *discordgo.Message) (*uploadJob, int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job := &uploadJob{m: m}
+	select {
+	case q.jobs <- job:
+	default:
+		return nil, 0, reject("Too many sounds are being converted, try again in a minute.")
+	}
+
+	if old, ok := q.byUser[m.Author.ID]; ok {
+		old.superseded = true
+		if old.cancel != nil {
+			old.cancel()
+		}
+	}
+	q.byUser[m.Author.ID] = job
+
+	return job, len(q.jobs), nil
+}
+
+// noticed remembers the reply telling the queue position, false if the job
+// has started meanwhile and the reply is stale already.
+func (q *uploadQueue) noticed(job *uploadJob, messageID string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if job.started {
+		return false
+	}
+	job.notice = messageID
+
+	return true
+}
+
+// start returns the context the job runs with, false if a newer upload
+// replaced it while it was waiting.
+func (q *uploadQueue) start(ctx context.Context, job *uploadJob) (context.Context, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job.started = true
+	if job.superseded {
+		return nil, false
+	}
+
+	var jobCtx context.Context
+	if q.timeout > 0 {
+		jobCtx, job.cancel = context.WithTimeout(ctx, q.timeout)
+	} else {
+		jobCtx, job.cancel = context.WithCancel(ctx)
+	}
+
+	return jobCtx, true
+}
+
+func (q *uploadQueue) isSuperseded(job *uploadJob) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return job.superseded
+}
+
+func (q *uploadQueue) finish(job *uploadJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.byUser[job.m.Author.ID] == job {
+		delete(q.byUser, job.m.Author.ID)
+	}
+	if job.cancel != nil {
+		job.cancel()
+	}
+}
+
+func (w *WelcomeVoice) runUploads(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-w.uploads.jobs:
+			w.runUpload(ctx, job)
+		}
+	}
+}
+
+// runUpload converts the job's sound without holding the lock and installs
+// it under the lock. Messages left on shutdown are picked up on the next
+// start.
+func (w *WelcomeVoice) runUpload(ctx context.Context, job *uploadJob) {
+	m := job.m
+	defer func() {
+		if err := w.client.MessageReactionRemove(m.ChannelID, m.ID, queuedEmoji, "@me"); err != nil {
+			w.logger.Printf("upload: reaction remove: %s", err.Error())
+		}
+	}()
+
+	jobCtx, ok := w.uploads.start(ctx, job)
+	if job.notice != "" {
+		_ = w.client.ChannelMessageDelete(m.ChannelID, job.notice)
+	}
+	if !ok {
+		_ = w.client.ChannelMessageDelete(m.ChannelID, m.ID)
+		return
+	}
+	defer w.uploads.finish(job)
+
+	staged, original, err := w.stageSound(jobCtx, m)
+	if err == nil {
+		defer os.Remove(staged)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	discard := func() {
+		if original != nil {
+			w.removeOriginal(original)
+		}
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		discard()
+		return
+	case w.uploads.isSuperseded(job):
+		w.logger.Printf("upload: message %s: replaced by a newer upload", m.ID)
+		discard()
+		_ = w.client.ChannelMessageDelete(m.ChannelID, m.ID)
+		return
+	case errors.Is(jobCtx.Err(), context.DeadlineExceeded):
+		discard()
+		err = reject("The sound took longer than %s to convert.", w.uploads.timeout)
+	}
+
+	if err == nil {
+		err = w.installUpload(m, staged, original)
+	}
+	if err != nil {
+		w.logger.Printf("upload: message %s: %s", m.ID, err.Error())
+		w.replyReject(m, err)
+		_ = w.client.ChannelMessageDelete(m.ChannelID, m.ID)
+	}
+}
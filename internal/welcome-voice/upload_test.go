@@ -0,0 +1,56 @@
+package welcomevoice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func uploadMessage(id, userID string) *discordgo.Message {
+	return &discordgo.Message{ID: id, Author: &discordgo.User{ID: userID}}
+}
+
+func TestUploadQueuePosition(t *testing.T) {
+	q := newUploadQueue(ConvertConfig{QueueSize: 2})
+
+	for i, m := range []*discordgo.Message{uploadMessage("m1", "1"), uploadMessage("m2", "2")} {
+		_, position, err := q.enqueue(m)
+		if err != nil {
+			t.Fatalf("enqueue(%s) error = %v", m.ID, err)
+		}
+		if position != i+1 {
+			t.Errorf("enqueue(%s) position = %d, want %d", m.ID, position, i+1)
+		}
+	}
+
+	if _, _, err := q.enqueue(uploadMessage("m3", "3")); err == nil {
+		t.Error("enqueue() on a full queue error = nil")
+	}
+}
+
+func TestUploadQueueSupersede(t *testing.T) {
+	q := newUploadQueue(ConvertConfig{QueueSize: 2})
+
+	old, _, err := q.enqueue(uploadMessage("m1", "1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.noticed(old, "r1") {
+		t.Error("noticed() = false before the start")
+	}
+
+	if _, _, err := q.enqueue(uploadMessage("m2", "1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := q.start(context.Background(), old); ok {
+		t.Error("start() of a superseded job = true")
+	}
+	if q.noticed(old, "r2") {
+		t.Error("noticed() = true after the start")
+	}
+	if old.notice != "r1" {
+		t.Errorf("notice = %q, want r1", old.notice)
+	}
+}
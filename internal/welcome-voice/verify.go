@@ -0,0 +1,381 @@
+package welcomevoice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	verifyActive   = "sound"
+	verifyPending  = "pending sound"
+	verifyFallback = "fallback sound"
+	verifyHistory  = "history version"
+)
+
+// verifyTarget is a stored sound file to check.
+type verifyTarget struct {
+	Kind    string
+	UserID  string
+	Version string
+	Path    string
+}
+
+func (t verifyTarget) String() string {
+	if t.Version != "" {
+		return fmt.Sprintf("%s %s of <@%s>", t.Kind, t.Version, t.UserID)
+	}
+
+	return fmt.Sprintf("%s of <@%s>", t.Kind, t.UserID)
+}
+
+type verifyReport struct {
+	Checked int
+	Fixed   []string
+	Failed  []string
+}
+
+func (r verifyReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Sound library check: %d files checked, %d fixed, %d could not be fixed.", r.Checked, len(r.Fixed), len(r.Failed))
+	for _, f := range r.Fixed {
+		fmt.Fprintf(&b, "\n✓ %s", f)
+	}
+	for _, f := range r.Failed {
+		fmt.Fprintf(&b, "\n✗ %s", f)
+	}
+
+	return b.String()
+}
+
+// runVerify checks the library on start and then every VerifyInterval.
+func (w *WelcomeVoice) runVerify(ctx context.Context) {
+	for {
+		report := w.verifyLibrary(ctx)
+		if len(report.Fixed) > 0 || len(report.Failed) > 0 {
+			w.logger.Print(report.String())
+
+			if w.config.VerifyReportChannelID != "" {
+				if _, err := w.client.ChannelMessageSend(w.config.VerifyReportChannelID, report.String()); err != nil {
+					w.logger.Printf("verify: report: %s", err.Error())
+				}
+			}
+		}
+
+		if w.config.VerifyInterval.Duration <= 0 {
+			return
+		}
+
+		select {
+		case <-time.After(w.config.VerifyInterval.Duration):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (w *WelcomeVoice) verifyLibrary(ctx context.Context) verifyReport {
+	w.mu.Lock()
+	targets, err := w.verifyTargets()
+	w.mu.Unlock()
+	if err != nil {
+		w.logger.Printf("verify: list: %s", err.Error())
+		return verifyReport{}
+	}
+
+	var report verifyReport
+	for _, t := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+
+		w.verifyOne(ctx, t, &report)
+	}
+
+	return report
+}
+
+// verifyOne checks the file without the lock, sounds are only ever
+// replaced by a rename.
+func (w *WelcomeVoice) verifyOne(ctx context.Context, t verifyTarget, report *verifyReport) {
+	report.Checked++
+
+	err := checkSound(t.Path)
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	w.logger.Printf("verify: %s is broken: %s", t, err.Error())
+
+	how, err := w.repairSound(ctx, t)
+	if err != nil {
+		report.Failed = append(report.Failed, fmt.Sprintf("%s: %s", t, err.Error()))
+		return
+	}
+
+	report.Fixed = append(report.Fixed, fmt.Sprintf("%s: %s", t, how))
+}
+
+// repairSound re-derives the broken file. It returns what was done. The
+// sources are looked up and the result is put in place under the lock, the
+// download and the conversion run without it.
+func (w *WelcomeVoice) repairSound(ctx context.Context, t verifyTarget) (string, error) {
+	if t.Kind != verifyActive && t.Kind != verifyPending {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		return w.dropSound(t)
+	}
+
+	w.mu.Lock()
+	original, messageID, ok := w.repairSources(t)
+	w.mu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("not in the queue")
+	}
+
+	ctx, cancel := w.withConvertTimeout(ctx)
+	defer cancel()
+
+	f, err := os.CreateTemp(path.Join(w.config.VoiceDir, stagingDir), "verify-*"+voiceExtension)
+	if err != nil {
+		return "", fmt.Errorf("create temp: %w", err)
+	}
+	_ = f.Close()
+	staged := f.Name()
+	defer os.Remove(staged)
+
+	how, rebuilt, rebuildErr := w.rebuildSound(ctx, t, original, messageID, staged)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// The user may have replaced or removed the sound meanwhile.
+	if err := checkSound(t.Path); err == nil || errors.Is(err, os.ErrNotExist) {
+		if rebuilt != nil {
+			w.removeOriginal(rebuilt)
+		}
+
+		return "replaced meanwhile", nil
+	}
+
+	if rebuildErr != nil {
+		return w.dropSound(t)
+	}
+
+	if err := os.Rename(staged, t.Path); err != nil {
+		if rebuilt != nil {
+			w.removeOriginal(rebuilt)
+		}
+
+		return "", fmt.Errorf("rename: %w", err)
+	}
+
+	switch t.Kind {
+	case verifyActive:
+		if rebuilt != nil {
+			w.setOriginal(t.UserID, rebuilt)
+		}
+
+		// The new file is not linked to the blob and its hash changed.
+		if err := w.dedupSound(t.UserID); err != nil {
+			w.logger.Printf("verify: %s: dedup: %s", t, err.Error())
+		}
+	case verifyPending:
+		if p, ok := w.repository.Pending[t.UserID]; ok && rebuilt != nil {
+			p.Original = rebuilt
+			w.saveRepository()
+		}
+	}
+
+	return how, nil
+}
+
+// repairSources returns what the sound can be rebuilt from, false if the
+// sound is not known anymore.
+func (w *WelcomeVoice) repairSources(t verifyTarget) (*originalSound, string, bool) {
+	if t.Kind == verifyPending {
+		p, ok := w.repository.Pending[t.UserID]
+		if !ok {
+			return nil, "", false
+		}
+
+		return p.Original, p.MessageID, true
+	}
+
+	// After a rollback the active sound is the last history version, not the
+	// sound of the newest message, which would undo the rollback.
+	messageID := w.messageByUser[t.UserID]
+	if versions := w.repository.History[t.UserID]; len(versions) > 0 && versions[len(versions)-1].MessageID != messageID {
+		messageID = ""
+	}
+
+	return w.repository.Originals[t.UserID], messageID, true
+}
+
+// rebuildSound converts the original upload again or, failing that,
+// downloads the message again. The new original is returned in the latter
+// case.
+func (w *WelcomeVoice) rebuildSound(ctx context.Context, t verifyTarget, original *originalSound, messageID, to string) (string, *originalSound, error) {
+	if original != nil {
+		err := w.convertSound(ctx, w.pathOriginalData(original), to, original.Trim)
+		if err == nil {
+			err = checkSound(to)
+		}
+		if err == nil {
+			return "re-encoded from the original upload", nil, nil
+		}
+		w.logger.Printf("verify: %s: re-encode: %s", t, err.Error())
+	}
+
+	if messageID != "" {
+		rebuilt, err := w.prepareMessage(ctx, messageID, to)
+		if err == nil {
+			return "downloaded again from the message", rebuilt, nil
+		}
+		w.logger.Printf("verify: %s: prepare message: %s", t, err.Error())
+	}
+
+	return "", nil, fmt.Errorf("no source left")
+}
+
+// prepareMessage converts the sound of a message from the channel again.
+func (w *WelcomeVoice) prepareMessage(ctx context.Context, messageID, to string) (*originalSound, error) {
+	m, err := w.client.ChannelMessage(w.config.ChannelID, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("channel message: %w", err)
+	}
+
+	original, err := w.prepareSound(ctx, m, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSound(to); err != nil {
+		w.removeOriginal(original)
+		return nil, fmt.Errorf("check: %w", err)
+	}
+
+	return original, nil
+}
+
+// dropSound removes the file which could not be re-derived.
+func (w *WelcomeVoice) dropSound(t verifyTarget) (string, error) {
+	switch t.Kind {
+	case verifyActive:
+		if err := os.Remove(t.Path); err != nil {
+			return "", fmt.Errorf("remove: %w", err)
+		}
+		w.releaseSound(t.UserID)
+
+		return "", fmt.Errorf("no source left, removed")
+	case verifyPending:
+		if err := w.rejectSound(t.UserID, "the sound could not be processed, please upload it again"); err != nil {
+			return "", fmt.Errorf("reject: %w", err)
+		}
+
+		return "", fmt.Errorf("no source left, rejected")
+	case verifyFallback:
+		delete(w.repository.Fallbacks, t.UserID)
+		w.saveRepository()
+
+		if err := os.Remove(t.Path); err != nil {
+			return "", fmt.Errorf("remove: %w", err)
+		}
+
+		return "removed, a new one is picked on the next join", nil
+	case verifyHistory:
+		versions := w.repository.History[t.UserID]
+		for i, v := range versions {
+			if w.pathHistoryData(t.UserID, v.ID) == t.Path {
+				w.repository.History[t.UserID] = append(versions[:i:i], versions[i+1:]...)
+				break
+			}
+		}
+		w.saveRepository()
+
+		if err := os.Remove(t.Path); err != nil {
+			return "", fmt.Errorf("remove: %w", err)
+		}
+
+		return "removed from the history", nil
+	default:
+		return "", fmt.Errorf("unknown kind %s", t.Kind)
+	}
+}
+
+func (w *WelcomeVoice) verifyTargets() ([]verifyTarget, error) {
+	var targets []verifyTarget
+
+	for _, dir := range []struct {
+		kind string
+		dir  string
+		path func(string) string
+	}{
+		{verifyActive, "", w.pathSoundData},
+		{verifyPending, pendingDir, w.pathPendingData},
+		{verifyFallback, fallbackDir, w.pathFallbackData},
+	} {
+		userIDs, err := w.storedUsers(path.Join(w.config.VoiceDir, dir.dir))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", dir.kind, err)
+		}
+
+		for _, userID := range userIDs {
+			targets = append(targets, verifyTarget{Kind: dir.kind, UserID: userID, Path: dir.path(userID)})
+		}
+	}
+
+	for userID, versions := range w.repository.History {
+		for _, v := range versions {
+			targets = append(targets, verifyTarget{
+				Kind:    verifyHistory,
+				UserID:  userID,
+				Version: v.CreatedAt.Format(time.DateTime),
+				Path:    w.pathHistoryData(userID, v.ID),
+			})
+		}
+	}
+
+	return targets, nil
+}
+
+// storedUsers lists users with a sound in the directory.
+func (w *WelcomeVoice) storedUsers(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+
+	var userIDs []string
+	for _, e := range entries {
+		userID, ok := strings.CutSuffix(e.Name(), voiceExtension)
+		if !e.Type().IsRegular() || !ok || strings.Trim(userID, "0123456789") != "" {
+			continue
+		}
+
+		userIDs = append(userIDs, userID)
+	}
+
+	return userIDs, nil
+}
+
+// checkSound parses the whole file as play would.
+func checkSound(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+
+	info, err := inspectOgg(path)
+	if err != nil {
+		return err
+	}
+	if info.Duration == 0 {
+		return fmt.Errorf("empty: %w", ErrIncompatibleOgg)
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package welcomevoice
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestVerifyRepairsFromOriginal(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{
+		// Passthrough copies the original, no ffmpeg is needed.
+		Convert: ConvertConfig{DisableNormalize: true, DisableTrimSilence: true},
+	})
+
+	original := writeOpus(t, 312, []uint64{960, 1920}, nil)
+	o, err := w.storeOriginal("m1", original, trimRange{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.repository.Originals["1"] = o
+
+	if err := os.WriteFile(w.pathSoundData("1"), []byte("broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.dedupSound("1"); err != nil {
+		t.Fatal(err)
+	}
+
+	report := w.verifyLibrary(context.Background())
+	if len(report.Fixed) != 1 || len(report.Failed) != 0 {
+		t.Fatalf("verifyLibrary() = %+v, want one fixed", report)
+	}
+
+	if err := checkSound(w.pathSoundData("1")); err != nil {
+		t.Errorf("repaired sound: %v", err)
+	}
+
+	want, err := hashFile(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.repository.Sounds["1"]; got != want {
+		t.Errorf("hash = %s, want %s", got, want)
+	}
+
+	blob, err := os.Stat(w.pathBlobData(want))
+	if err != nil {
+		t.Fatalf("blob: %v", err)
+	}
+	sound, err := os.Stat(w.pathSoundData("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(blob, sound) {
+		t.Error("sound is not linked to the blob")
+	}
+}
+
+func TestVerifyDropsBrokenHistory(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{})
+
+	v := &soundVersion{ID: "v1", CreatedAt: time.Now()}
+	w.repository.History["1"] = []*soundVersion{v}
+	if err := os.MkdirAll(path.Dir(w.pathHistoryData("1", v.ID)), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(w.pathHistoryData("1", v.ID), []byte("broken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := w.verifyLibrary(context.Background())
+	if len(report.Fixed) != 1 {
+		t.Fatalf("verifyLibrary() = %+v, want one fixed", report)
+	}
+	if len(w.repository.History["1"]) != 0 {
+		t.Errorf("history = %v, want empty", w.repository.History["1"])
+	}
+	if _, err := os.Stat(w.pathHistoryData("1", v.ID)); !os.IsNotExist(err) {
+		t.Errorf("history file is kept: %v", err)
+	}
+}
+
+func TestRepairSourcesAfterRollback(t *testing.T) {
+	w := newTestWelcomeVoice(t, Config{})
+	target := verifyTarget{Kind: verifyActive, UserID: "1", Path: w.pathSoundData("1")}
+
+	w.messageByUser["1"] = "m2"
+	w.repository.History["1"] = []*soundVersion{{ID: "v1", MessageID: "m1"}, {ID: "v2", MessageID: "m2"}}
+	if _, messageID, _ := w.repairSources(target); messageID != "m2" {
+		t.Errorf("repairSources() message = %q, want m2", messageID)
+	}
+
+	// The rollback moves v1 to the end, the newest message is not the
+	// active sound anymore.
+	w.repository.History["1"] = []*soundVersion{{ID: "v2", MessageID: "m2"}, {ID: "v1", MessageID: "m1"}}
+	if _, messageID, _ := w.repairSources(target); messageID != "" {
+		t.Errorf("repairSources() message = %q, want none after a rollback", messageID)
+	}
+}
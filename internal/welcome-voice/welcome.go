@@ -7,10 +7,8 @@ import (
 	"fmt"
 	"io"
 	"log"
-	"mime"
 	"net/http"
 	"os"
-	"os/exec"
 	"path"
 	"sync"
 	"time"
@@ -28,32 +26,102 @@ const (
 type WelcomeVoice struct {
 	config        Config
 	client        *discordgo.Session
+	httpClient    *http.Client
+	random        RandomSoundProvider
 	logger        *log.Logger
 	channelByUser map[string]string
 	messageByUser map[string]string
-	mu            sync.Mutex
-
+	// soundboardUsed is when the user last played a sound on demand.
+	soundboardUsed map[string]time.Time
+	repository     *repository
+	spam           *spamGuard
+	schedule       *schedule
+	synthesizer    Synthesizer
+	// batches are the greetings waiting to be mixed, by channel.
+	batches map[string]*greetingBatch
+	uploads *uploadQueue
+	mu      sync.Mutex
+	// voice serializes playbacks, also those running without mu.
+	voice sync.Mutex
+
+	// ctx is cancelled on shutdown to abort downloads in handlers.
+	ctx      context.Context
 	shutdown []func() error
 }
 
 func New(config Config, client *discordgo.Session, logger *log.Logger) (*WelcomeVoice, error) {
 	logger.SetPrefix("[Welcome Voice]: ")
 
+	if !validFallbackPolicy(config.FallbackPolicy) {
+		return nil, fmt.Errorf("fallback policy %q: %w", config.FallbackPolicy, ErrUnknownPolicy)
+	}
+	if config.Moderation.Enabled && len(config.Moderation.RoleIDs) == 0 {
+		return nil, fmt.Errorf("moderation: %w", ErrNoModerators)
+	}
+	if !validMixMode(config.Mix.Mode) {
+		return nil, fmt.Errorf("mix mode %q: %w", config.Mix.Mode, ErrUnknownMixMode)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	w := &WelcomeVoice{
-		config:        config,
-		client:        client,
-		logger:        logger,
-		channelByUser: make(map[string]string),
-		messageByUser: make(map[string]string),
+		config:         config,
+		client:         client,
+		httpClient:     newUploadClient(config.DownloadTimeout.Duration),
+		logger:         logger,
+		channelByUser:  make(map[string]string),
+		messageByUser:  make(map[string]string),
+		soundboardUsed: make(map[string]time.Time),
+		batches:        make(map[string]*greetingBatch),
+		spam:           newSpamGuard(),
+		uploads:        newUploadQueue(config.Convert),
+		ctx:            ctx,
+	}
+	w.shutdown = append(w.shutdown, func() error {
+		cancel()
+		return nil
+	})
+	w.shutdown = append(w.shutdown, func() error {
+		w.stopBatches()
+		return nil
+	})
+
+	// Staged sounds left by a crash are never installed.
+	if err := os.RemoveAll(path.Join(config.VoiceDir, stagingDir)); err != nil {
+		return nil, fmt.Errorf("clean staging dir: %w", err)
+	}
+
+	for _, dir := range []string{stagingDir, fallbackDir, pendingDir, historyDir, originalDir, ttsDir, defaultsDir, blobDir} {
+		if err := os.MkdirAll(path.Join(config.VoiceDir, dir), os.ModePerm); err != nil {
+			return nil, fmt.Errorf("mkdir voice dir: %w", err)
+		}
+	}
+
+	repo, err := w.readRepository()
+	if errors.Is(err, os.ErrNotExist) {
+		repo = newRepository()
+	} else if err != nil {
+		return nil, fmt.Errorf("read repository: %w", err)
+	}
+	w.repository = repo
+
+	random, err := NewRandomSoundProvider(config.Random)
+	if err != nil {
+		return nil, fmt.Errorf("random sound provider: %w", err)
 	}
+	w.random = random
 
-	if err := os.MkdirAll(config.VoiceDir, os.ModePerm); err != nil {
-		return nil, fmt.Errorf("mkdir voice dir: %w", err)
+	schedule, err := newSchedule(config.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("schedule: %w", err)
 	}
+	w.schedule = schedule
 
-	if err := mime.AddExtensionType(".mp3", "audio/mpeg3"); err != nil {
-		return nil, fmt.Errorf("add extension: %w", err)
+	synthesizer, err := NewSynthesizer(config.TTS)
+	if err != nil {
+		return nil, fmt.Errorf("synthesizer: %w", err)
 	}
+	w.synthesizer = synthesizer
 
 	cancelConnect := w.client.AddHandler(w.onConnect)
 	w.shutdown = append(w.shutdown, func() error {
@@ -65,16 +133,62 @@ func New(config Config, client *discordgo.Session, logger *log.Logger) (*Welcome
 		cancelMessageCreate()
 		return nil
 	})
+	cancelReactionAdd := w.client.AddHandler(w.onReactionAdd)
+	w.shutdown = append(w.shutdown, func() error {
+		cancelReactionAdd()
+		return nil
+	})
+	cancelCommand := w.client.AddHandler(w.onCommand)
+	w.shutdown = append(w.shutdown, func() error {
+		cancelCommand()
+		return nil
+	})
+	cancelInteraction := w.client.AddHandler(w.onInteraction)
+	w.shutdown = append(w.shutdown, func() error {
+		cancelInteraction()
+		return nil
+	})
+
+	if config.SlashCommands {
+		if err := w.registerSlashCommands(); err != nil {
+			return nil, fmt.Errorf("register slash commands: %w", err)
+		}
+	}
 
 	if err := w.loadMessage(); err != nil {
 		return nil, fmt.Errorf("load message: %w", err)
 	}
 
+	if err := w.migrateFallbacks(); err != nil {
+		return nil, fmt.Errorf("migrate fallbacks: %w", err)
+	}
+
+	if err := w.dedupLibrary(); err != nil {
+		return nil, fmt.Errorf("dedup library: %w", err)
+	}
+
 	return w, nil
 }
 
 func (w *WelcomeVoice) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.runVerify(ctx)
+	}()
+
+	for i := 0; i < w.uploads.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.runUploads(ctx)
+		}()
+	}
+
 	<-ctx.Done()
+	wg.Wait()
 
 	var errs []error
 	for _, s := range w.shutdown {
@@ -87,18 +201,35 @@ func (w *WelcomeVoice) Run(ctx context.Context) error {
 }
 
 func (w *WelcomeVoice) loadMessage() error {
-	var beforeID string
+	var (
+		beforeID string
+		pending  = make(map[string]bool)
+	)
 	for {
 		messages, err := w.client.ChannelMessages(w.config.ChannelID, 100, beforeID, "", "")
 		if err != nil {
 			return fmt.Errorf("channel messages: %w", err)
 		}
 		if len(messages) == 0 {
-			return nil
+			break
 		}
 		beforeID = messages[len(messages)-1].ID
 
 		for _, m := range messages {
+			if m.Author.ID == w.client.State.User.ID || w.isCommand(m.Content) {
+				continue
+			}
+
+			if w.config.Moderation.Enabled {
+				loaded, err := w.loadPending(m, pending)
+				if err != nil {
+					return fmt.Errorf("load pending: %w", err)
+				}
+				if loaded {
+					continue
+				}
+			}
+
 			if _, ok := w.messageByUser[m.Author.ID]; ok {
 				if err := w.client.ChannelMessageDelete(m.ChannelID, m.ID); err != nil {
 					return fmt.Errorf("remove old message: %w", err)
@@ -107,18 +238,11 @@ func (w *WelcomeVoice) loadMessage() error {
 				continue
 			}
 
-			markAsDone := slices.IndexFunc(m.Reactions, func(r *discordgo.MessageReactions) bool {
-				if r.Emoji.Name != w.config.Emoji {
-					return false
-				}
-
-				return r.Me
-			}) != -1
-
 			_, err := os.Stat(w.pathSoundData(m.Author.ID))
-			if errors.Is(err, os.ErrNotExist) || !markAsDone {
-				if err := w.prepareSound(m); err != nil {
+			if errors.Is(err, os.ErrNotExist) || !w.markedAsDone(m) {
+				if err := w.uploadSound(w.ctx, m, w.activateSound); err != nil {
 					w.logger.Printf("load message: prepare: %s", err.Error())
+					w.replyReject(m, err)
 
 					if err := w.client.ChannelMessageDelete(m.ChannelID, m.ID); err != nil {
 						return fmt.Errorf("remove message: %w", err)
@@ -133,128 +257,262 @@ func (w *WelcomeVoice) loadMessage() error {
 			w.messageByUser[m.Author.ID] = m.ID
 		}
 	}
-}
 
-func (w *WelcomeVoice) prepareSound(m *discordgo.Message) error {
-	if len(m.Attachments) != 1 {
-		return ErrAttachmentsNotFound
-	}
+	w.dropStalePending(pending)
 
-	attach := m.Attachments[0]
+	return nil
+}
 
-	if attach.Size > voiceMaxSize {
-		return ErrVoiceTooLarge
-	}
+// markedAsDone reports whether the bot has accepted the message's sound.
+func (w *WelcomeVoice) markedAsDone(m *discordgo.Message) bool {
+	return slices.IndexFunc(m.Reactions, func(r *discordgo.MessageReactions) bool {
+		if r.Emoji.Name != w.config.Emoji {
+			return false
+		}
+
+		return r.Me
+	}) != -1
+}
 
-	path, err := w.downloadSound(attach.ProxyURL)
+// uploadSound stages the message's sound and hands it to install, which
+// moves the staged file into place.
+func (w *WelcomeVoice) uploadSound(ctx context.Context, m *discordgo.Message, install installFunc) error {
+	staged, original, err := w.stageSound(ctx, m)
 	if err != nil {
-		return fmt.Errorf("download sound: %w", err)
+		return err
 	}
-	defer os.Remove(path)
+	defer os.Remove(staged)
 
-	if err := w.convertSound(path, w.pathSoundData(m.Author.ID)); err != nil {
-		return fmt.Errorf("conver sound: %w", err)
+	if err := w.checkBanned(staged, original); err != nil {
+		w.removeOriginal(original)
+		return err
 	}
 
-	if err := w.client.MessageReactionAdd(m.ChannelID, m.ID, w.config.Emoji); err != nil {
-		return fmt.Errorf("reaction add: %w", err)
+	return install(m, staged, original)
+}
+
+// installFunc puts a staged sound in use.
+type installFunc func(m *discordgo.Message, staged string, original *originalSound) error
+
+// stageSound converts the message's sound into the staging directory, so
+// the sounds in use are only touched once the conversion succeeded.
+func (w *WelcomeVoice) stageSound(ctx context.Context, m *discordgo.Message) (string, *originalSound, error) {
+	staged := path.Join(w.config.VoiceDir, stagingDir, m.ID+voiceExtension)
+
+	original, err := w.prepareSound(ctx, m, staged)
+	if err != nil {
+		_ = os.Remove(staged)
+		return "", nil, err
 	}
 
-	return nil
+	return staged, original, nil
 }
 
-func (w *WelcomeVoice) randomSound(userID string) error {
-	path, err := w.downloadSound("http://api.cleanvoice.ru/myinstants/?type=file")
-	if err != nil {
-		return fmt.Errorf("download: %w", err)
+// activateSound makes the staged sound the user's active sound.
+func (w *WelcomeVoice) activateSound(m *discordgo.Message, staged string, original *originalSound) error {
+	if err := os.Rename(staged, w.pathSoundData(m.Author.ID)); err != nil {
+		return fmt.Errorf("rename: %w", err)
 	}
-	defer os.Remove(path)
+	w.setOriginal(m.Author.ID, original)
 
-	if err := w.convertSound(path, w.pathSoundData(userID)); err != nil {
-		return fmt.Errorf("conver sound: %w", err)
+	if err := w.client.MessageReactionAdd(m.ChannelID, m.ID, w.config.Emoji); err != nil {
+		return fmt.Errorf("reaction add: %w", err)
 	}
 
 	return nil
 }
 
-func (w *WelcomeVoice) downloadSound(uri string) (string, error) {
-	r, err := http.Get(uri)
+// prepareSound converts the message's sound and keeps the original upload.
+func (w *WelcomeVoice) prepareSound(ctx context.Context, m *discordgo.Message, to string) (*originalSound, error) {
+	uri, content, err := soundSource(m)
 	if err != nil {
-		return "", fmt.Errorf("get: %w", err)
+		return nil, fmt.Errorf("sound source: %w", err)
 	}
-	defer r.Body.Close()
 
-	ct := r.Header.Get("content-type")
-
-	exts, err := mime.ExtensionsByType(ct)
+	trim, err := parseTrimRange(content)
 	if err != nil {
-		return "", fmt.Errorf("extension %s: %w", ct, err)
-	}
-	if len(exts) < 1 {
-		return "", fmt.Errorf("extension %s: %w", ct, ErrExtensionNotFound)
+		return nil, fmt.Errorf("parse trim: %w", err)
 	}
 
-	f, err := os.CreateTemp("", "mog-*"+exts[0])
+	path, err := w.downloadSound(ctx, uri)
 	if err != nil {
-		return "", fmt.Errorf("crate temp: %w", err)
+		return nil, fmt.Errorf("download sound: %w", err)
 	}
-	defer f.Close()
+	defer os.Remove(path)
 
-	if _, err := io.Copy(f, r.Body); err != nil {
-		return "", fmt.Errorf("download: %w", err)
-	}
+	if !trim.IsZero() {
+		clip, err := probeDuration(ctx, path)
+		if err != nil {
+			return nil, fmt.Errorf("probe duration: %w", err)
+		}
 
-	return f.Name(), nil
-}
+		if err := w.validateTrim(trim, clip); err != nil {
+			return nil, fmt.Errorf("validate trim: %w", err)
+		}
+	}
 
-func (w *WelcomeVoice) convertSound(from, to string) error {
-	cmd := exec.Command("ffmpeg", "-y", "-vn", "-i", from, "-c:a", "libopus", "-page_duration", "20000", to)
+	if err := w.convertSound(ctx, path, to, trim); err != nil {
+		return nil, fmt.Errorf("conver sound: %w", err)
+	}
 
-	output, err := cmd.CombinedOutput()
+	original, err := w.storeOriginal(m.ID, path, trim)
 	if err != nil {
-		return fmt.Errorf("ffmpeg: %s, %w", string(output), err)
+		return nil, fmt.Errorf("store original: %w", err)
 	}
-	w.logger.Printf("to=%s: %s", to, string(output))
 
-	return nil
+	return original, nil
 }
 
 func (w *WelcomeVoice) pathSoundData(userID string) string {
 	return path.Join(w.config.VoiceDir, userID+voiceExtension)
 }
 
+// replyReject tells the author why the message was rejected, if the error
+// was caused by the message itself.
+func (w *WelcomeVoice) replyReject(m *discordgo.Message, err error) {
+	var reason string
+
+	var rejectErr *RejectError
+	switch {
+	case errors.As(err, &rejectErr):
+		reason = rejectErr.Reason
+	case errors.Is(err, ErrVoiceTooLarge):
+		reason = fmt.Sprintf("The sound is larger than %d MiB.", voiceMaxSize/1024/1024)
+	case errors.Is(err, ErrForbiddenAddress):
+		reason = "The link points to an address the bot does not download from."
+	default:
+		return
+	}
+
+	if _, err := w.client.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content:   m.Author.Mention() + " " + reason,
+		Reference: m.Reference(),
+	}); err != nil {
+		w.logger.Printf("reply reject: %s", err.Error())
+	}
+}
+
 func (w *WelcomeVoice) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
-	if m.ChannelID != w.config.ChannelID {
+	if m.ChannelID != w.config.ChannelID || m.Author.ID == w.client.State.User.ID {
 		return
 	}
 
-	w.mu.Lock()
-	defer w.mu.Unlock()
+	if w.isCommand(m.Content) {
+		return
+	}
 
-	if err := w.prepareSound(m.Message); err != nil {
-		w.logger.Printf("message create: prepare sound: %s", err.Error())
+	job, position, err := w.uploads.enqueue(m.Message)
+	if err != nil {
+		w.logger.Printf("message create: enqueue: %s", err.Error())
+		w.replyReject(m.Message, err)
 		_ = w.client.ChannelMessageDelete(m.ChannelID, m.ID)
 		return
 	}
 
-	oldMessageID, ok := w.messageByUser[m.Author.ID]
-	if ok {
-		if err := w.client.ChannelMessageDelete(m.ChannelID, oldMessageID); err != nil {
+	if err := w.client.MessageReactionAdd(m.ChannelID, m.ID, queuedEmoji); err != nil {
+		w.logger.Printf("message create: reaction add: %s", err.Error())
+	}
+
+	if position > 1 {
+		w.noticePosition(job, position)
+	}
+}
+
+// noticePosition replies with the position of the upload in the queue, the
+// reply is removed when the conversion starts.
+func (w *WelcomeVoice) noticePosition(job *uploadJob, position int) {
+	m := job.m
+
+	reply, err := w.client.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Content:   fmt.Sprintf("%s Your sound is number %d in the queue.", m.Author.Mention(), position),
+		Reference: m.Reference(),
+	})
+	if err != nil {
+		w.logger.Printf("message create: notice position: %s", err.Error())
+		return
+	}
+
+	if !w.uploads.noticed(job, reply.ID) {
+		_ = w.client.ChannelMessageDelete(m.ChannelID, reply.ID)
+	}
+}
+
+// installUpload puts the staged sound of a new message in use, or in the
+// moderation queue.
+func (w *WelcomeVoice) installUpload(m *discordgo.Message, staged string, original *originalSound) error {
+	if err := w.checkBanned(staged, original); err != nil {
+		w.removeOriginal(original)
+		return err
+	}
+
+	if w.config.Moderation.Enabled {
+		if err := w.submitSound(m, staged, original); err != nil {
+			return fmt.Errorf("submit sound: %w", err)
+		}
+		return nil
+	}
+
+	if err := w.activateSound(m, staged, original); err != nil {
+		return fmt.Errorf("activate sound: %w", err)
+	}
+
+	w.commitSound(m.Author.ID, m.ID, sourceName(m), m.GuildID)
+	w.postPreview(m.Author.ID, m)
+
+	return nil
+}
+
+// commitSound makes the message the source of the user's active sound and
+// plays the new sound if the user is in a voice channel.
+func (w *WelcomeVoice) commitSound(userID, messageID, source, guildID string) {
+	oldMessageID, ok := w.messageByUser[userID]
+	if ok && oldMessageID != messageID {
+		if err := w.client.ChannelMessageDelete(w.config.ChannelID, oldMessageID); err != nil {
 			w.logger.Printf("remove old message: %s", err.Error())
 			return
 		}
 	}
 
-	w.messageByUser[m.Author.ID] = m.ID
+	w.messageByUser[userID] = messageID
+	w.removeFallback(userID)
+	if _, ok := w.repository.Imports[userID]; ok {
+		delete(w.repository.Imports, userID)
+		w.saveRepository()
+	}
+
+	if err := w.dedupSound(userID); err != nil {
+		w.logger.Printf("dedup sound: %s", err.Error())
+	}
 
-	channelID, ok := w.channelByUser[m.Author.ID]
+	if err := w.recordHistory(userID, messageID, source); err != nil {
+		w.logger.Printf("record history: %s", err.Error())
+	}
+
+	// The caller holds the lock, the sound is played once it is released.
+	if _, ok := w.channelByUser[userID]; ok {
+		go w.playNewSound(userID, guildID)
+	}
+}
+
+// playNewSound plays the user's new sound without holding the lock during
+// the playback.
+func (w *WelcomeVoice) playNewSound(userID, guildID string) {
+	w.mu.Lock()
+	channelID, ok := w.channelByUser[userID]
+	w.mu.Unlock()
 	if !ok {
 		return
 	}
 
-	if err := w.play(m.Author.ID, m.GuildID, channelID); err != nil {
+	sound := w.pathSoundData(userID)
+	p, err := w.play(sound, guildID, channelID)
+
+	w.mu.Lock()
+	w.recordPlayback(playKindUpload, userID, sound, guildID, channelID, p, err)
+	w.mu.Unlock()
+
+	if err != nil {
 		w.logger.Printf("play new sound: %s", err.Error())
-		return
 	}
 }
 
@@ -264,62 +522,138 @@ func (w *WelcomeVoice) onConnect(_ *discordgo.Session, u *discordgo.VoiceStateUp
 	}
 
 	w.mu.Lock()
-	defer w.mu.Unlock()
-
-	if u.BeforeUpdate != nil {
-		delete(w.channelByUser, u.UserID)
+	volume, ok := w.admitGreeting(u)
+	w.mu.Unlock()
+	if !ok {
 		return
 	}
 
-	w.channelByUser[u.UserID] = u.ChannelID
+	// The sound is prepared and played without the lock, w.voice keeps
+	// greetings from overlapping.
+	sound, err := w.userSound(w.ctx, u.UserID, u.GuildID, u.Member)
+	if err != nil {
+		w.logger.Printf("on connect: user sound: %s", err.Error())
+		return
+	}
 
-	if err := w.play(u.UserID, u.GuildID, u.ChannelID); errors.Is(err, os.ErrNotExist) {
-		if err := w.randomSound(u.UserID); err != nil {
-			w.logger.Printf("on connect: random prepare: %s", err.Error())
-			return
-		}
-		if err := w.play(u.UserID, u.GuildID, u.ChannelID); err != nil {
-			w.logger.Printf("on connect: random play: %s", err.Error())
+	g := &batchGreeting{
+		userID: u.UserID,
+		sound:  sound,
+		played: sound,
+	}
+	if volume < 1 {
+		ctx, cancel := w.withConvertTimeout(w.ctx)
+		quiet, err := scaleVolume(ctx, sound, volume)
+		cancel()
+		if err != nil {
+			w.logger.Printf("on connect: scale volume: %s", err.Error())
 			return
 		}
-	} else if err != nil {
-		w.logger.Printf("on connect: play: %s", err.Error())
+
+		g.played = quiet
+		g.temporary = true
+	}
+
+	if w.config.Mix.Mode != "" {
+		w.mu.Lock()
+		w.batchGreeting(u.GuildID, u.ChannelID, g)
+		w.mu.Unlock()
 		return
 	}
+	defer g.close()
+
+	p, err := w.play(g.played, u.GuildID, u.ChannelID)
+
+	w.mu.Lock()
+	w.recordPlayback(playKindGreeting, u.UserID, sound, u.GuildID, u.ChannelID, p, err)
+	w.mu.Unlock()
+
+	if err != nil {
+		w.logger.Printf("on connect: play: %s", err.Error())
+	}
+}
+
+// admitGreeting tracks the user's channel and tells whether the join is
+// greeted and how loud. Without mixing the cooldowns start right away, so
+// a rejoin while the sound is prepared is not greeted again.
+func (w *WelcomeVoice) admitGreeting(u *discordgo.VoiceStateUpdate) (float64, bool) {
+	if u.BeforeUpdate != nil {
+		delete(w.channelByUser, u.UserID)
+		return 0, false
+	}
+
+	w.channelByUser[u.UserID] = u.ChannelID
+
+	if reason := w.presenceSkip(u.UserID, u.GuildID, u.ChannelID); reason != "" {
+		w.logger.Printf("on connect: skip %s in %s: %s", u.UserID, u.ChannelID, reason)
+		return 0, false
+	}
+
+	if !w.allowGreeting(u.UserID, u.ChannelID) {
+		return 0, false
+	}
+
+	if !w.wantsGreeting(u.UserID, w.channelListeners(u.GuildID, u.ChannelID)) {
+		return 0, false
+	}
+
+	volume := w.schedule.greetingVolume(u.GuildID, time.Now())
+	if volume == 0 {
+		return 0, false
+	}
+
+	if w.config.Mix.Mode == "" {
+		w.greeted(u.UserID, u.ChannelID)
+	}
+
+	return volume, true
 }
 
-func (w *WelcomeVoice) play(userID, guildID, channelID string) error {
-	f, err := os.Open(w.pathSoundData(userID))
+// play sends the sound to the voice channel. The playback tells how much was
+// played, also if it failed midway.
+func (w *WelcomeVoice) play(sound, guildID, channelID string) (*playback, error) {
+	w.voice.Lock()
+	defer w.voice.Unlock()
+
+	p := &playback{}
+
+	f, err := os.Open(sound)
 	if err != nil {
-		return fmt.Errorf("open file: %w", err)
+		return p, fmt.Errorf("open file: %w", err)
 	}
 	defer f.Close()
 
 	voice, err := w.client.ChannelVoiceJoin(guildID, channelID, false, true)
 	if err != nil {
-		return fmt.Errorf("channel voice join: %w", err)
+		return p, fmt.Errorf("channel voice join: %w", err)
 	}
 	defer func() { _ = voice.Disconnect() }()
 
-	reader, _, err := oggreader.NewWith(bufio.NewReader(f))
+	reader, header, err := oggreader.NewWith(bufio.NewReader(f))
 	if err != nil {
-		return fmt.Errorf("ogg reader: %w", err)
+		return p, fmt.Errorf("ogg reader: %w", err)
 	}
 
 	t := time.NewTimer(w.config.VoiceDuration.Duration)
 	for {
-		data, _, err := reader.ParseNextPage()
+		data, page, err := reader.ParseNextPage()
 		if errors.Is(err, io.EOF) {
-			return nil
+			return p, nil
 		}
 		if err != nil {
-			return fmt.Errorf("parse ogg: %w", err)
+			return p, fmt.Errorf("parse ogg: %w", err)
 		}
 
 		select {
 		case voice.OpusSend <- data:
+			// The granule position counts 48 kHz samples up to the end of
+			// the page, including the pre-skip.
+			if samples := int64(page.GranulePosition) - int64(header.PreSkip); samples > 0 {
+				p.Played = time.Duration(samples) * time.Second / opusSampleRate
+			}
 		case <-t.C:
-			return nil
+			p.CutShort = true
+			return p, nil
 		}
 	}
 }